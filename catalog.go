@@ -0,0 +1,229 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type errorCoder interface {
+	ErrorCode() string
+}
+
+// Catalog holds translations of error messages for multiple languages.
+//
+// Translations are keyed by keys registered for base errors using
+// Register, or by error codes of errors which implement the following
+// interface:
+//
+//	type errorCoder interface {
+//	        ErrorCode() string
+//	}
+//
+// The zero value is an empty catalog without any translations.
+// Use NewCatalog to load translations.
+//
+// Catalog is safe for concurrent use.
+type Catalog struct {
+	// DefaultLanguage is the language used when there is no
+	// translation available for the requested language.
+	DefaultLanguage string
+
+	mu           sync.RWMutex
+	translations map[string]map[string]string
+	keys         map[error]string
+}
+
+// NewCatalog returns a new catalog with translations loaded from fsys.
+//
+// Every file with the .json extension in the root of fsys contains
+// translations for one language, with the file name (without the extension)
+// being the language tag (e.g., "en.json" or "pt-BR.json").
+// A file contains a JSON object mapping keys to translated messages.
+//
+// Translated messages can contain placeholders in the form of "{name}"
+// which are replaced with values of details with the same name.
+func NewCatalog(fsys fs.FS) (*Catalog, E) {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, WithStack(err)
+	}
+
+	translations := make(map[string]map[string]string, len(files))
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, WithDetails(err, "file", file)
+		}
+		var messages map[string]string
+		err = json.Unmarshal(data, &messages)
+		if err != nil {
+			return nil, WithDetails(err, "file", file)
+		}
+		lang := normalizeLanguage(strings.TrimSuffix(path.Base(file), ".json"))
+		if translations[lang] == nil {
+			translations[lang] = messages
+		} else {
+			for key, message := range messages {
+				translations[lang][key] = message
+			}
+		}
+	}
+
+	return &Catalog{
+		DefaultLanguage: "",
+		mu:              sync.RWMutex{},
+		translations:    translations,
+		keys:            map[error]string{},
+	}, nil
+}
+
+// Register registers key under which translations for the base error
+// are found in the catalog.
+//
+// The base error is matched by its identity, so it has to be comparable.
+func (c *Catalog) Register(base error, key string) {
+	if base == nil || !reflect.TypeOf(base).Comparable() {
+		panic(Errorf(`base error must be comparable, not %T`, base))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keys == nil {
+		c.keys = map[error]string{}
+	}
+	c.keys[base] = key
+}
+
+func (c *Catalog) keyOf(err error) string {
+	if reflect.TypeOf(err).Comparable() {
+		key, ok := c.keys[err]
+		if ok {
+			return key
+		}
+	}
+	coder, ok := err.(errorCoder)
+	if ok {
+		return coder.ErrorCode()
+	}
+	return ""
+}
+
+// translate traverses err's tree depth-first and returns the translation
+// for the first (i.e., the most specific) error which has it.
+//
+// ancestors are errors already traversed on the path to err, used to
+// detect cycles.
+func (c *Catalog) translate(err error, messages map[string]string, ancestors []error) (string, bool) {
+	for err != nil && !isAncestor(err, ancestors) {
+		ancestors = append(ancestors[:len(ancestors):len(ancestors)], err)
+		key := c.keyOf(err)
+		if key != "" {
+			message, ok := messages[key]
+			if ok {
+				return message, true
+			}
+		}
+		switch u := err.(type) { //nolint:errorlint
+		case unwrapperJoined:
+			for _, er := range u.Unwrap() {
+				message, ok := c.translate(er, messages, ancestors)
+				if ok {
+					return message, true
+				}
+			}
+			return "", false
+		case unwrapper:
+			err = u.Unwrap()
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// Localize returns the error message of err translated to language lang.
+//
+// Localize traverses err's tree depth-first (in the same order as Is does)
+// and uses the translation for the first error with a translation. This means
+// that errors higher in the tree (e.g., a base error made with BaseWrap
+// which wraps another base error) take precedence as they are more specific.
+//
+// Placeholders in the translation are filled with values from AllDetails
+// of err. Placeholders without a corresponding detail are left as-is.
+//
+// If there is no translation for lang, languages obtained by removing
+// trailing subtags from lang (e.g., "pt" for "pt-BR") are tried, and then
+// the same for DefaultLanguage. If there is still no translation,
+// Localize returns PublicMessage of err.
+func (c *Catalog) Localize(err error, lang string) string {
+	if err == nil {
+		return ""
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, l := range append(languageFallbacks(lang), languageFallbacks(c.DefaultLanguage)...) {
+		messages, ok := c.translations[l]
+		if !ok {
+			continue
+		}
+		message, ok := c.translate(err, messages, nil)
+		if ok {
+			return fillPlaceholders(message, AllDetails(err))
+		}
+	}
+
+	return PublicMessage(err)
+}
+
+func normalizeLanguage(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+}
+
+// languageFallbacks returns lang followed by languages obtained
+// by repeatedly removing the last subtag.
+func languageFallbacks(lang string) []string {
+	lang = normalizeLanguage(lang)
+	languages := []string{}
+	for lang != "" {
+		languages = append(languages, lang)
+		i := strings.LastIndex(lang, "-")
+		if i < 0 {
+			break
+		}
+		lang = lang[:i]
+	}
+	return languages
+}
+
+func fillPlaceholders(message string, details map[string]interface{}) string {
+	result := strings.Builder{}
+	for {
+		start := strings.Index(message, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(message[start:], "}")
+		if end < 0 {
+			break
+		}
+		end += start
+		value, ok := details[message[start+1:end]]
+		result.WriteString(message[:start])
+		if ok {
+			result.WriteString(fmt.Sprint(value))
+		} else {
+			result.WriteString(message[start : end+1])
+		}
+		message = message[end+1:]
+	}
+	result.WriteString(message)
+	return result.String()
+}
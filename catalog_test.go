@@ -0,0 +1,110 @@
+package errors_test
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+type codeError struct {
+	code string
+}
+
+func (e *codeError) Error() string {
+	return "code error"
+}
+
+func (e *codeError) ErrorCode() string {
+	return e.code
+}
+
+func TestCatalog(t *testing.T) {
+	t.Parallel()
+
+	errNotFound := errors.Base("not found")
+	errUserNotFound := errors.BaseWrap(errNotFound, "user not found")
+	errOther := errors.Base("other")
+
+	catalog, errE := errors.NewCatalog(fstest.MapFS{
+		"en.json": &fstest.MapFile{Data: []byte(`{
+			"notFound": "Not found.",
+			"userNotFound": "User {username} not found.",
+			"code": "Error with code."
+		}`)},
+		"sl.json": &fstest.MapFile{Data: []byte(`{
+			"notFound": "Ni najdeno.",
+			"userNotFound": "Uporabnik {username} ni najden ({missing})."
+		}`)},
+		"pt_BR.json": &fstest.MapFile{Data: []byte(`{
+			"notFound": "Não encontrado."
+		}`)},
+		"README.md": &fstest.MapFile{Data: []byte(`Not a translation.`)},
+	})
+	require.NoError(t, errE, "% -+#.1v", errE)
+	catalog.DefaultLanguage = "en"
+	catalog.Register(errNotFound, "notFound")
+	catalog.Register(errUserNotFound, "userNotFound")
+
+	tests := []struct {
+		err  error
+		lang string
+		want string
+	}{
+		{nil, "en", ""},
+		{errors.WithStack(errNotFound), "en", "Not found."},
+		{errors.WithStack(errNotFound), "sl", "Ni najdeno."},
+		{errors.WithStack(errNotFound), "SL-si", "Ni najdeno."},
+		{errors.WithStack(errNotFound), "pt-BR", "Não encontrado."},
+		{errors.WithStack(errNotFound), "de", "Not found."},
+		{errors.WithDetails(errUserNotFound, "username", "joe"), "en", "User joe not found."},
+		{errors.WithDetails(errUserNotFound, "username", "joe"), "sl", "Uporabnik joe ni najden ({missing})."},
+		{errors.WithDetails(errUserNotFound, "username", "joe"), "pt-BR", "Não encontrado."},
+		{errors.Errorf("failed: %w", errUserNotFound), "en", "User {username} not found."},
+		{errors.WrapWith(errors.New("error"), errNotFound), "en", "Not found."},
+		{errors.WithStack(&codeError{"code"}), "sl", "Error with code."},
+		{errors.WithStack(&codeError{"unknown"}), "en", "unknown"},
+		{errors.WithPublicMessage(errOther, "Public."), "en", "Public."},
+		{errors.WithStack(errOther), "en", ""},
+	}
+
+	for k, tt := range tests {
+		tt := tt
+
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, catalog.Localize(tt.err, tt.lang))
+		})
+	}
+}
+
+func TestCatalogInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, errE := errors.NewCatalog(fstest.MapFS{
+		"en.json": &fstest.MapFile{Data: []byte(`["invalid"]`)},
+	})
+	require.Error(t, errE)
+	assert.Equal(t, "en.json", errors.AllDetails(errE)["file"])
+
+	catalog, errE := errors.NewCatalog(fstest.MapFS{})
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Panics(t, func() {
+		catalog.Register(nil, "key")
+	})
+}
+
+func TestCatalogZero(t *testing.T) {
+	t.Parallel()
+
+	base := errors.Base("not found")
+
+	var catalog errors.Catalog
+	catalog.Register(base, "notFound")
+	assert.Equal(t, "Not found.", catalog.Localize(errors.WithPublicMessage(base, "Not found."), "en"))
+}
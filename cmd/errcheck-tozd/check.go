@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+const errorsPath = "gitlab.com/tozd/go/errors"
+
+type diagnostic struct {
+	Pos     token.Position
+	Message string
+}
+
+func (d diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Pos, d.Message)
+}
+
+// checkDirs checks Go packages in dirs. A directory ending with "/..."
+// is checked recursively, skipping testdata, vendor, and hidden directories.
+func checkDirs(dirs []string) ([]diagnostic, errors.E) {
+	expanded := []string{}
+	for _, dir := range dirs {
+		if dir != "..." && !strings.HasSuffix(dir, "/...") {
+			expanded = append(expanded, dir)
+			continue
+		}
+		root := strings.TrimSuffix(strings.TrimSuffix(dir, "..."), "/")
+		if root == "" {
+			root = "."
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			name := d.Name()
+			if path != root && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			expanded = append(expanded, path)
+			return nil
+		})
+		if err != nil {
+			return nil, errors.WithDetails(err, "dir", dir)
+		}
+	}
+
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil)
+	diagnostics := []diagnostic{}
+	for _, dir := range expanded {
+		d, errE := checkDir(fset, imp, dir)
+		if errE != nil {
+			return nil, errE
+		}
+		diagnostics = append(diagnostics, d...)
+	}
+	return diagnostics, nil
+}
+
+// checkDir parses and type-checks all packages (including test packages)
+// in dir and checks them. Files excluded by build constraints for the
+// current platform are skipped.
+func checkDir(fset *token.FileSet, imp types.Importer, dir string) ([]diagnostic, errors.E) {
+	filter := func(info fs.FileInfo) bool {
+		match, err := build.Default.MatchFile(dir, info.Name())
+		return err == nil && match
+	}
+	pkgs, err := parser.ParseDir(fset, dir, filter, parser.ParseComments) //nolint:staticcheck
+	if err != nil {
+		return nil, errors.WithDetails(err, "dir", dir)
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.WithDetails(err, "dir", dir)
+	}
+
+	names := make([]string, 0, len(pkgs))
+	for name := range pkgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	diagnostics := []diagnostic{}
+	for _, name := range names {
+		files := make([]*ast.File, 0, len(pkgs[name].Files))
+		filenames := make([]string, 0, len(pkgs[name].Files))
+		for filename := range pkgs[name].Files {
+			filenames = append(filenames, filename)
+		}
+		sort.Strings(filenames)
+		for _, filename := range filenames {
+			files = append(files, pkgs[name].Files[filename])
+		}
+		diagnostics = append(diagnostics, checkFiles(fset, imp, absDir, files)...)
+	}
+	return diagnostics, nil
+}
+
+// checkFiles type-checks files of one package and checks them.
+// Type errors are ignored and checks are done on whatever
+// type information is available.
+func checkFiles(fset *token.FileSet, imp types.Importer, dir string, files []*ast.File) []diagnostic {
+	info := &types.Info{ //nolint:exhaustruct
+		Types: map[ast.Expr]types.TypeAndValue{},
+		Defs:  map[*ast.Ident]types.Object{},
+		Uses:  map[*ast.Ident]types.Object{},
+	}
+	config := &types.Config{ //nolint:exhaustruct
+		Importer: importerFrom{imp, dir},
+		Error:    func(error) {},
+	}
+	_, _ = config.Check(dir, fset, files, info)
+
+	c := &checker{fset: fset, info: info, diagnostics: []diagnostic{}}
+	for _, file := range files {
+		c.checkFile(file)
+	}
+	return c.diagnostics
+}
+
+// importerFrom imports packages relative to dir.
+type importerFrom struct {
+	importer types.Importer
+	dir      string
+}
+
+func (i importerFrom) Import(path string) (*types.Package, error) {
+	from, ok := i.importer.(types.ImporterFrom)
+	if ok {
+		return from.ImportFrom(path, i.dir, 0) //nolint:wrapcheck
+	}
+	return i.importer.Import(path) //nolint:wrapcheck
+}
+
+type checker struct {
+	fset        *token.FileSet
+	info        *types.Info
+	diagnostics []diagnostic
+}
+
+func (c *checker) report(node ast.Node, format string, args ...interface{}) {
+	c.diagnostics = append(c.diagnostics, diagnostic{
+		Pos:     c.fset.Position(node.Pos()),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// unparen returns expr with any enclosing parentheses removed.
+func unparen(expr ast.Expr) ast.Expr {
+	for {
+		p, ok := expr.(*ast.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = p.X
+	}
+}
+
+// errorsFunc returns the name of the function from this package
+// called by call, or an empty string.
+func (c *checker) errorsFunc(call *ast.CallExpr) string {
+	var ident *ast.Ident
+	switch fun := unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return ""
+	}
+	fn, ok := c.info.Uses[ident].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != errorsPath {
+		return ""
+	}
+	return fn.Name()
+}
+
+// isE returns true if typ is errors.E.
+func isE(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == errorsPath && obj.Name() == "E"
+}
+
+// isPlainError returns true if typ is the standard error interface.
+func isPlainError(typ types.Type) bool {
+	return typ != nil && types.Identical(typ, types.Universe.Lookup("error").Type())
+}
+
+func (c *checker) checkFile(file *ast.File) {
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok == token.VAR {
+				c.checkPackageVar(d)
+			}
+			c.checkExprs(d, nil)
+		case *ast.FuncDecl:
+			var sig *types.Signature
+			if obj, ok := c.info.Defs[d.Name].(*types.Func); ok {
+				sig, _ = obj.Type().(*types.Signature)
+			}
+			c.checkExprs(d, sig)
+		}
+	}
+}
+
+// checkPackageVar reports New and Errorf calls at package level.
+func (c *checker) checkPackageVar(decl *ast.GenDecl) {
+	for _, spec := range decl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		for _, value := range valueSpec.Values {
+			ast.Inspect(value, func(node ast.Node) bool {
+				switch n := node.(type) {
+				case *ast.FuncLit:
+					// Function literals are not called at initialization.
+					return false
+				case *ast.CallExpr:
+					switch c.errorsFunc(n) {
+					case "New":
+						c.report(n, "errors.New at package level records a stack trace during initialization, use errors.Base instead")
+					case "Errorf":
+						c.report(n, "errors.Errorf at package level records a stack trace during initialization, use errors.Basef instead")
+					}
+				}
+				return true
+			})
+		}
+	}
+}
+
+// checkExprs checks calls and return statements inside node.
+// sig is the signature of the function containing node, if any.
+func (c *checker) checkExprs(node ast.Node, sig *types.Signature) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			var litSig *types.Signature
+			if typ := c.info.TypeOf(n); typ != nil {
+				litSig, _ = typ.(*types.Signature)
+			}
+			c.checkExprs(n.Body, litSig)
+			return false
+		case *ast.ReturnStmt:
+			c.checkReturn(n, sig)
+		case *ast.CallExpr:
+			c.checkCall(n)
+		}
+		return true
+	})
+}
+
+// checkReturn reports returning a plain error from a function returning E.
+// Such code does not compile, so this reports it only in code which does not
+// compile yet, explaining how to fix the resulting type error.
+func (c *checker) checkReturn(ret *ast.ReturnStmt, sig *types.Signature) {
+	if sig == nil || sig.Results().Len() != len(ret.Results) {
+		return
+	}
+	for i, result := range ret.Results {
+		if !isE(sig.Results().At(i).Type()) {
+			continue
+		}
+		if isPlainError(c.info.TypeOf(result)) {
+			c.report(result, "returning plain error from function returning errors.E, use errors.WithStack")
+		}
+	}
+}
+
+func (c *checker) checkCall(call *ast.CallExpr) {
+	switch c.errorsFunc(call) {
+	case "WithStack":
+		if len(call.Args) == 1 && isE(c.info.TypeOf(call.Args[0])) {
+			c.report(call, "redundant errors.WithStack on value of type errors.E")
+		}
+	case "WithDetails":
+		if call.Ellipsis == token.NoPos && len(call.Args) > 0 && (len(call.Args)-1)%2 != 0 {
+			c.report(call, "odd number of key-value arguments to errors.WithDetails")
+		}
+	case "WrapWith":
+		if len(call.Args) == 2 && c.info.Types[call.Args[1]].IsNil() { //nolint:mnd
+			c.report(call, `errors.WrapWith with nil "with" argument panics`)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var wantRegexp = regexp.MustCompile(`// want "([^"]+)"`)
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	diagnostics, errE := checkDirs([]string{"testdata/..."})
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "testdata/example/example.go", nil, parser.ParseComments)
+	require.NoError(t, err)
+
+	want := map[int]string{}
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			match := wantRegexp.FindStringSubmatch(comment.Text)
+			if match != nil {
+				want[fset.Position(comment.Pos()).Line] = match[1]
+			}
+		}
+	}
+	require.NotEmpty(t, want)
+
+	got := map[int]string{}
+	for _, d := range diagnostics {
+		assert.True(t, strings.HasSuffix(d.Pos.Filename, "example.go"), d.Pos.Filename)
+		got[d.Pos.Line] = d.Message
+	}
+
+	for line, message := range want {
+		if assert.Contains(t, got, line, "missing diagnostic on line %d", line) {
+			assert.Contains(t, got[line], message)
+		}
+	}
+	for line, message := range got {
+		assert.Contains(t, want, line, "unexpected diagnostic on line %d: %s", line, message)
+	}
+}
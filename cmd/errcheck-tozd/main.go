@@ -0,0 +1,53 @@
+// Command errcheck-tozd reports misuse of the gitlab.com/tozd/go/errors package.
+//
+// Usage:
+//
+//	errcheck-tozd [directory ...]
+//
+// Directories default to the current directory. A directory ending with "/..."
+// is checked recursively. Reported are:
+//
+//   - errors.New and errors.Errorf called at package level, where errors.Base and
+//     errors.Basef should be used instead so that stack traces are recorded correctly
+//   - returning a plain error from a function returning errors.E, without errors.WithStack
+//     (such code does not compile, so this is reported only for code with type errors)
+//   - redundant errors.WithStack on values already of type errors.E
+//   - an odd number of key-value arguments to errors.WithDetails
+//   - errors.WrapWith called with nil "with" argument
+//
+// The command exits with exit code 1 if any issue is found.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func main() {
+	found := false
+	errors.Main(func() error {
+		var errE errors.E
+		found, errE = run(os.Args[1:])
+		return errE
+	})
+	if found {
+		os.Exit(1)
+	}
+}
+
+func run(dirs []string) (bool, errors.E) {
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	diagnostics, errE := checkDirs(dirs)
+	if errE != nil {
+		return false, errE
+	}
+	for _, d := range diagnostics {
+		fmt.Fprintln(os.Stdout, d)
+	}
+	return len(diagnostics) > 0, nil
+}
@@ -0,0 +1,10 @@
+//go:build go1.18
+// +build go1.18
+
+package example
+
+import (
+	"gitlab.com/tozd/go/errors"
+)
+
+var ErrConstraint = errors.Base("constraint")
@@ -0,0 +1,11 @@
+//go:build !go1.18
+// +build !go1.18
+
+package example
+
+import (
+	"gitlab.com/tozd/go/errors"
+)
+
+// This file is excluded by its build constraint and must not be checked.
+var ErrConstraint = errors.New("constraint")
@@ -0,0 +1,58 @@
+package example
+
+import (
+	"gitlab.com/tozd/go/errors"
+)
+
+var (
+	ErrBase    = errors.Base("base")
+	ErrNew     = errors.New("new")       // want "errors.New at package level"
+	ErrErrorf  = errors.Errorf("errorf") // want "errors.Errorf at package level"
+	ErrWrapped = errors.Basef("%w", ErrBase)
+	makeErr    = func() error { return errors.New("lazy") }
+)
+
+func plain() error {
+	return ErrBase
+}
+
+func returnsPlain() errors.E {
+	err := plain()
+	if err != nil {
+		return err // want "returning plain error from function returning errors.E"
+	}
+	return nil
+}
+
+func returnsWithStack() (int, errors.E) {
+	err := plain()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return 1, nil
+}
+
+func redundant() errors.E {
+	errE := errors.New("error")
+	return errors.WithStack(errE) // want "redundant errors.WithStack"
+}
+
+func details(kv ...interface{}) errors.E {
+	_ = errors.WithDetails(ErrBase, "key")          // want "odd number of key-value arguments"
+	_ = errors.WithDetails(ErrBase, "key", "value") // OK.
+	return errors.WithDetails(ErrBase, kv...)
+}
+
+func wrapWith() errors.E {
+	_ = errors.WrapWith(ErrBase, nil) // want "errors.WrapWith with nil"
+	return errors.WrapWith(ErrBase, ErrWrapped)
+}
+
+func closure() {
+	_ = func() errors.E {
+		return plain() // want "returning plain error from function returning errors.E"
+	}
+	_ = func() error {
+		return plain()
+	}
+}
@@ -0,0 +1,84 @@
+package errors
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// ANSI escape codes used to color parts of formatted errors.
+const (
+	colorReset    = "\x1b[0m"
+	colorMessage  = "\x1b[1;31m"
+	colorKey      = "\x1b[36m"
+	colorValue    = "\x1b[33m"
+	colorFunction = "\x1b[32m"
+	colorLocation = "\x1b[90m"
+)
+
+// ColorEnabled returns true if colored output should be written to w.
+//
+// It returns false if the NO_COLOR environment variable is set to a non-empty
+// value (see https://no-color.org/) or if w is not a terminal. Use it to set
+// Color field of Formatter:
+//
+//	fmt.Fprintf(os.Stderr, "% -+#.1v", errors.Formatter{Error: err, Color: errors.ColorEnabled(os.Stderr)})
+func ColorEnabled(w io.Writer) bool {
+	if noColor() {
+		return false
+	}
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func noColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// useColor returns true if o should color its output.
+func (o FormatOptions) useColor() bool {
+	return o.Color && !noColor()
+}
+
+// colorLines wraps every non-empty line of s with color, if o should color its output.
+func (o FormatOptions) colorLines(color, s string) string {
+	if !o.useColor() {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = color + line + colorReset
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// colorStack colors a formatted stack trace, if o should color its output.
+// Lines with function names are not indented while lines
+// with file:line locations are.
+func (o FormatOptions) colorStack(s string) string {
+	if !o.useColor() {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if trimmed == line {
+			lines[i] = colorFunction + line + colorReset
+		} else {
+			lines[i] = line[:len(line)-len(trimmed)] + colorLocation + trimmed + colorReset
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,55 @@
+package errors_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func TestColor(t *testing.T) { //nolint:paralleltest
+	// We cannot use t.Parallel with t.Setenv.
+	t.Setenv("NO_COLOR", "")
+
+	err := errors.WithDetails(errors.New("error"), "key", "value")
+
+	assert.Equal(t, "error\nkey=value\n", fmt.Sprintf("%#v", errors.Formatter{Error: err}))
+	assert.Equal(t, "\x1b[1;31merror\x1b[0m\n\x1b[36mkey\x1b[0m=\x1b[33mvalue\x1b[0m\n", fmt.Sprintf("%#v", errors.Formatter{Error: err, Color: true}))
+	// Plain verbs are not colored.
+	assert.Equal(t, "error", fmt.Sprintf("%v", errors.Formatter{Error: err, Color: true}))
+
+	got := fmt.Sprintf("%+v", errors.Formatter{Error: err, Color: true})
+	assert.Regexp(t, "^\x1b\\[1;31merror\x1b\\[0m\n"+
+		"\x1b\\[32mgitlab.com/tozd/go/errors_test.TestColor\x1b\\[0m\n"+
+		"\t\x1b\\[90m.+/color_test.go:\\d+\x1b\\[0m\n", got)
+
+	// Format colors only when writing to a terminal.
+	var buf bytes.Buffer
+	errE := errors.Format(&buf, err, errors.FormatOptions{Details: true, Color: true})
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, "error\nkey=value\n", buf.String())
+	assert.Equal(t, "\x1b[1;31merror\x1b[0m\n\x1b[36mkey\x1b[0m=\x1b[33mvalue\x1b[0m\n", errors.Sprint(err, errors.FormatOptions{Details: true, Color: true}))
+
+	t.Setenv("NO_COLOR", "1")
+
+	assert.Equal(t, "error\nkey=value\n", fmt.Sprintf("%#v", errors.Formatter{Error: err, Color: true}))
+}
+
+func TestColorEnabled(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, errors.ColorEnabled(&bytes.Buffer{}))
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "file"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		f.Close()
+	})
+	assert.False(t, errors.ColorEnabled(f))
+}
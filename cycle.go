@@ -0,0 +1,161 @@
+package errors
+
+import (
+	"runtime"
+	"strconv"
+)
+
+// maxUnmarshalDepth is the maximum depth of nested causes and joined
+// errors UnmarshalJSON recurses into.
+const maxUnmarshalDepth = 1000
+
+// JSON field used for back-references to an error which has already
+// been marshaled, when the tree of errors contains a cycle.
+const jsonRef = "$ref"
+
+// cycleMessage returns the text used in place of an error
+// which has already been formatted as error number n.
+func cycleMessage(n int) string {
+	return "<cycle: see error #" + strconv.Itoa(n) + ">"
+}
+
+// unwrapCycle detects cycles when errors are repeatedly unwrapped.
+// It uses Brent's algorithm so that it does not allocate, because
+// it is used on hot paths (e.g., when errors are made).
+//
+// The zero value is ready to use.
+type unwrapCycle struct {
+	slow  error
+	power int
+	steps int
+}
+
+// repeated returns true if err has already been passed to repeated,
+// i.e., unwrapping has entered a cycle. A cycle is detected at the
+// latest after unwrapping twice around the cycle.
+func (c *unwrapCycle) repeated(err error) bool {
+	if c.slow == nil {
+		c.slow = err
+		c.power = 1
+		return false
+	}
+	if isSameError(err, c.slow) {
+		return true
+	}
+	c.steps++
+	if c.steps == c.power {
+		c.slow = err
+		c.power *= 2
+		c.steps = 0
+	}
+	return false
+}
+
+// visitedError is an error on the path from the root of the tree of
+// errors which is currently being formatted or marshaled.
+type visitedError struct {
+	err error
+	// The number of the error in the order errors are formatted, starting with 1.
+	number int
+	// JSON pointer to the error, relative to the root.
+	path string
+}
+
+// visitedErrors tracks errors on the path from the root of the tree of
+// errors which is currently being formatted or marshaled, to detect cycles.
+type visitedErrors struct {
+	path  []visitedError
+	count int
+	// rewriteFrame, if set, rewrites frames of marshaled stack traces.
+	rewriteFrame func(runtime.Frame) (runtime.Frame, bool)
+}
+
+// find returns the error on the current path which is the same as err.
+func (v *visitedErrors) find(err error) (visitedError, bool) {
+	if v == nil {
+		return visitedError{}, false
+	}
+	for _, e := range v.path {
+		if isSameError(err, e.err) {
+			return e, true
+		}
+	}
+	return visitedError{}, false
+}
+
+// findPath returns the error on the current path with JSON pointer path.
+func (v *visitedErrors) findPath(path string) (visitedError, bool) {
+	if v == nil {
+		return visitedError{}, false
+	}
+	for _, e := range v.path {
+		if e.path == path {
+			return e, true
+		}
+	}
+	return visitedError{}, false
+}
+
+// push adds err at JSON pointer path to the current path.
+func (v *visitedErrors) push(err error, path string) {
+	v.count++
+	v.path = append(v.path, visitedError{err: err, number: v.count, path: path})
+}
+
+// pop removes the last error from the current path.
+func (v *visitedErrors) pop() {
+	v.path = v.path[:len(v.path)-1]
+}
+
+// current returns JSON pointer to the last error on the current path.
+func (v *visitedErrors) current() string {
+	if v == nil || len(v.path) == 0 {
+		return "#"
+	}
+	return v.path[len(v.path)-1].path
+}
+
+// backReference returns the text used in place of err if err
+// would make a cycle, i.e., if it is already on the current path.
+// Placeholder back-references made by UnmarshalJSON are resolved as well.
+func (v *visitedErrors) backReference(err error) (string, bool) {
+	if e, ok := v.find(err); ok {
+		return cycleMessage(e.number), true
+	}
+	r, ok := err.(*placeholderRefError) //nolint:errorlint
+	if ok {
+		if e, ok := v.findPath(r.ref); ok {
+			return cycleMessage(e.number), true
+		}
+		return r.Error(), true
+	}
+	return "", false
+}
+
+// placeholderRefError represents a back-reference to an error
+// which made a cycle when the tree of errors was marshaled.
+//
+// It is made by UnmarshalJSON from a JSON object with only the "$ref"
+// field and is marshaled back into the same JSON object.
+type placeholderRefError struct {
+	ref string
+}
+
+func (e *placeholderRefError) ownError() {}
+
+func (e *placeholderRefError) Error() string {
+	return "<cycle: see " + e.ref + ">"
+}
+
+func (e *placeholderRefError) MarshalJSON() ([]byte, error) {
+	return marshalJSONRef(e.ref)
+}
+
+// marshalJSONRef marshals a back-reference to the error at JSON pointer path.
+func marshalJSONRef(path string) ([]byte, E) {
+	data, err := marshalWithoutEscapeHTML(map[string]string{jsonRef: path})
+	if err != nil {
+		return nil, WithStack(err)
+	}
+	return data, nil
+}
@@ -0,0 +1,186 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+type causeCycleError struct {
+	msg   string
+	cause error
+}
+
+func (e *causeCycleError) Error() string {
+	return e.msg
+}
+
+func (e *causeCycleError) Cause() error {
+	return e.cause
+}
+
+func withoutStack(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var payload map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &payload))
+	delete(payload, "stack")
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+	return data
+}
+
+func TestCycles(t *testing.T) {
+	t.Parallel()
+
+	a := &causeCycleError{msg: "a"}
+	b := &causeCycleError{msg: "b", cause: a}
+	a.cause = b
+
+	self := errors.Base("self")
+	withSelf := errors.WithDetails(self)
+	errors.Details(withSelf)["self"] = withSelf
+	errors.Details(withSelf)["nested"] = map[string]interface{}{"list": []interface{}{withSelf}}
+
+	unwrapSelf := &cycleError{}
+	unwrapSelf.err = unwrapSelf
+
+	tests := []struct {
+		Name      string
+		Err       error
+		Formatted string
+		Tree      string
+		JSON      string
+	}{
+		{
+			"cause",
+			a,
+			"a\nb\n<cycle: see error #1>\n",
+			"a\n└─ cause: b\n   └─ cause: <cycle: see error #1>\n",
+			`{"error":"a","cause":{"error":"b","cause":{"$ref":"#"}}}`,
+		},
+		{
+			"details",
+			withSelf,
+			`self` + "\n" + `nested={"list":[{"$ref":"#"}]}` + "\n" + `self=<cycle: see error #1>` + "\n",
+			`self` + "\n" + `nested={"list":[{"$ref":"#"}]}` + "\n" + `self=<cycle: see error #1>` + "\n",
+			`{"error":"self","nested":{"list":[{"$ref":"#"}]},"self":{"$ref":"#"}}`,
+		},
+		{
+			"unwrap",
+			unwrapSelf,
+			"cycle\n",
+			"cycle\n",
+			`{"error":"cycle"}`,
+		},
+		{
+			"joined",
+			errors.Join(a, errors.Base("c")),
+			"a\nc\n\ta\n\tb\n\t<cycle: see error #2>\n\tc\n",
+			"a\nc\n├─ joined: a\n│  └─ cause: b\n│     └─ cause: <cycle: see error #2>\n└─ joined: c\n",
+			`{"error":"a\nc","errors":[{"error":"a","cause":{"error":"b","cause":{"$ref":"#/errors/0"}}},{"error":"c"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.Formatted, fmt.Sprintf("%#.1v", errors.Formatter{Error: tt.Err}))
+			assert.Equal(t, tt.Tree, fmt.Sprintf("%#.4v", errors.Formatter{Error: tt.Err}))
+
+			data, e := json.Marshal(errors.Formatter{Error: tt.Err})
+			require.NoError(t, e)
+			assert.JSONEq(t, tt.JSON, string(withoutStack(t, data)))
+
+			errors.AllDetails(tt.Err)
+			errors.Cause(tt.Err)
+			errors.Unjoin(errors.WithStack(tt.Err))
+			assert.NotEmpty(t, errors.Fingerprint(tt.Err))
+			assert.True(t, errors.Equal(tt.Err, tt.Err))
+
+			placeholder, errE := errors.UnmarshalJSON(data)
+			require.NoError(t, errE, "% -+#.1v", errE)
+			data2, e := json.Marshal(placeholder)
+			require.NoError(t, e)
+			assert.JSONEq(t, tt.JSON, string(withoutStack(t, data2)))
+		})
+	}
+}
+
+func TestCyclesMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	c := &causeCycleError{msg: "c"}
+	w := errors.Wrap(c, "w")
+	c.cause = w
+
+	self := errors.Base("self")
+	withSelf := errors.WithDetails(self)
+	errors.Details(withSelf)["self"] = withSelf
+
+	for _, err := range []error{w, withSelf} {
+		data, e := json.Marshal(err)
+		require.NoError(t, e)
+		formatterData, e := json.Marshal(errors.Formatter{Error: err})
+		require.NoError(t, e)
+		assert.JSONEq(t, string(formatterData), string(data))
+	}
+
+	data, e := json.Marshal(w)
+	require.NoError(t, e)
+	assert.JSONEq(t, `{"error":"w","cause":{"error":"c","cause":{"$ref":"#"}}}`, string(withoutStack(t, data)))
+}
+
+func TestCyclesPlaceholder(t *testing.T) {
+	t.Parallel()
+
+	placeholder, errE := errors.UnmarshalJSON([]byte(`{"error":"a","cause":{"error":"b","cause":{"$ref":"#"}}}`))
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, "a\nb\n<cycle: see error #1>\n", fmt.Sprintf("%#.1v", placeholder))
+	assert.Equal(t, "<cycle: see #>", errors.Cause(errors.Cause(placeholder)).Error())
+
+	// Back-references to errors which are not ancestors are not resolved.
+	placeholder, errE = errors.UnmarshalJSON([]byte(`{"error":"a","cause":{"$ref":"#/errors/0"}}`))
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, "a\n<cycle: see #/errors/0>\n", fmt.Sprintf("%#.1v", placeholder))
+}
+
+func TestCyclesEqual(t *testing.T) {
+	t.Parallel()
+
+	a := &causeCycleError{msg: "a"}
+	a.cause = a
+	b := &causeCycleError{msg: "a"}
+	b.cause = &causeCycleError{msg: "a", cause: b}
+
+	assert.True(t, errors.Equal(a, a))
+	assert.True(t, errors.Equal(b, b))
+	assert.Equal(t, "cause.cycle: 0 != <nil>", errors.Diff(a, b))
+}
+
+func TestUnmarshalJSONDepth(t *testing.T) {
+	t.Parallel()
+
+	data := strings.Repeat(`{"error":"x","cause":`, 2000) + `{"error":"x"}` + strings.Repeat(`}`, 2000)
+	placeholder, errE := errors.UnmarshalJSON([]byte(data))
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	depth := 0
+	for err := placeholder; err != nil; err = errors.Cause(err) {
+		depth++
+	}
+	assert.Equal(t, 1001, depth)
+
+	data2, e := json.Marshal(placeholder)
+	require.NoError(t, e)
+	assert.JSONEq(t, data, string(data2))
+}
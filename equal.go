@@ -0,0 +1,286 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type equalOptions struct {
+	stackFunctions    bool
+	ignoreDetails     map[string]bool
+	ignoreJoinedOrder bool
+}
+
+// EqualOption configures how Equal and Diff compare errors.
+type EqualOption func(*equalOptions)
+
+// CompareStackFunctions makes Equal and Diff compare stack traces
+// by names of functions in them. By default stack traces are ignored.
+func CompareStackFunctions() EqualOption {
+	return func(o *equalOptions) {
+		o.stackFunctions = true
+	}
+}
+
+// IgnoreDetails makes Equal and Diff ignore details with keys.
+func IgnoreDetails(keys ...string) EqualOption {
+	return func(o *equalOptions) {
+		for _, key := range keys {
+			o.ignoreDetails[key] = true
+		}
+	}
+}
+
+// IgnoreJoinedOrder makes Equal and Diff ignore the order of joined errors.
+// Lines of messages of errors with joined errors are then compared
+// regardless of their order, too.
+func IgnoreJoinedOrder() EqualOption {
+	return func(o *equalOptions) {
+		o.ignoreJoinedOrder = true
+	}
+}
+
+// Equal returns true if a and b are structurally equal.
+//
+// Errors are compared in the same way they are marshaled into JSON:
+// their messages, details (see AllDetails), causes (see Cause),
+// and joined errors (see Unjoin) are compared, recursively.
+// Details are compared by their JSON representation.
+// Stack traces are ignored unless CompareStackFunctions option is used.
+//
+// This means that placeholder errors obtained from UnmarshalJSON are
+// equal to errors they were marshaled from.
+func Equal(a, b error, opts ...EqualOption) bool {
+	return len(diffErrors(a, b, opts)) == 0
+}
+
+// Diff returns a description of structural differences between a and b,
+// one difference per line, each prefixed with the path to the error
+// where it was found (e.g., "cause.errors[1]"). It returns an empty
+// string if a and b are equal.
+//
+// Errors are compared in the same way as Equal compares them.
+func Diff(a, b error, opts ...EqualOption) string {
+	return strings.Join(diffErrors(a, b, opts), "\n")
+}
+
+func diffErrors(a, b error, opts []EqualOption) []string {
+	o := &equalOptions{
+		stackFunctions:    false,
+		ignoreDetails:     map[string]bool{},
+		ignoreJoinedOrder: false,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	diffs := []string{}
+	diffError("", a, b, o, diffAncestors{}, &diffs)
+	return diffs
+}
+
+func diffPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func addDiff(diffs *[]string, path, field string, a, b interface{}) {
+	*diffs = append(*diffs, fmt.Sprintf("%s: %#v != %#v", diffPath(path, field), a, b))
+}
+
+// diffAncestors are errors on paths from roots of compared errors,
+// used to detect cycles.
+type diffAncestors struct {
+	a []error
+	b []error
+}
+
+func (d diffAncestors) add(a, b error) diffAncestors {
+	return diffAncestors{
+		a: append(d.a[:len(d.a):len(d.a)], a),
+		b: append(d.b[:len(d.b):len(d.b)], b),
+	}
+}
+
+func diffError(path string, a, b error, o *equalOptions, ancestors diffAncestors, diffs *[]string) {
+	if a == nil || b == nil {
+		if a != nil || b != nil {
+			addDiff(diffs, path, "error", errorMessage(a), errorMessage(b))
+		}
+		return
+	}
+
+	// Errors which make a cycle are equal only if they both make
+	// a cycle to the ancestor at the same depth.
+	aAncestor := ancestorIndex(a, ancestors.a)
+	bAncestor := ancestorIndex(b, ancestors.b)
+	if aAncestor >= 0 || bAncestor >= 0 {
+		if aAncestor != bAncestor {
+			addDiff(diffs, path, "cycle", cycleDepth(aAncestor), cycleDepth(bAncestor))
+		}
+		return
+	}
+	ancestors = ancestors.add(a, b)
+
+	aDetails, aCause, aErrs := allDetailsUntilCauseOrJoined(a)
+	bDetails, bCause, bErrs := allDetailsUntilCauseOrJoined(b)
+	aJoined := joinedErrors(a, aCause, aErrs)
+	bJoined := joinedErrors(b, bCause, bErrs)
+
+	aMessage := a.Error()
+	bMessage := b.Error()
+	if o.ignoreJoinedOrder && (len(aJoined) > 0 || len(bJoined) > 0) {
+		// Messages of errors with joined errors generally contain
+		// messages of joined errors, in their order.
+		aMessage = sortedLines(aMessage)
+		bMessage = sortedLines(bMessage)
+	}
+	if aMessage != bMessage {
+		addDiff(diffs, path, "message", a.Error(), b.Error())
+	}
+
+	aNormalized := normalizeDetails(aDetails, o)
+	bNormalized := normalizeDetails(bDetails, o)
+	keys := make([]string, 0, len(aNormalized)+len(bNormalized))
+	for key := range aNormalized {
+		keys = append(keys, key)
+	}
+	for key := range bNormalized {
+		if _, ok := aNormalized[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		aValue, aOk := aNormalized[key]
+		bValue, bOk := bNormalized[key]
+		if aOk != bOk || !reflect.DeepEqual(aValue, bValue) {
+			addDiff(diffs, path, "details["+key+"]", aValue, bValue)
+		}
+	}
+
+	if o.stackFunctions {
+		aFunctions := frameFunctions(a)
+		bFunctions := frameFunctions(b)
+		if !reflect.DeepEqual(aFunctions, bFunctions) {
+			addDiff(diffs, path, "stack", aFunctions, bFunctions)
+		}
+	}
+
+	diffJoined(path, aJoined, bJoined, o, ancestors, diffs)
+
+	diffError(diffPath(path, "cause"), aCause, bCause, o, ancestors, diffs)
+}
+
+// sortedLines returns s with its lines sorted.
+func sortedLines(s string) string {
+	lines := strings.Split(s, "\n")
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// cycleDepth returns the depth of the ancestor to which an error
+// makes a cycle, or nil if it does not make a cycle.
+func cycleDepth(i int) interface{} {
+	if i < 0 {
+		return nil
+	}
+	return i
+}
+
+func diffJoined(path string, aErrs, bErrs []error, o *equalOptions, ancestors diffAncestors, diffs *[]string) {
+	if !o.ignoreJoinedOrder {
+		for i := 0; i < len(aErrs) || i < len(bErrs); i++ {
+			var aEr, bEr error
+			if i < len(aErrs) {
+				aEr = aErrs[i]
+			}
+			if i < len(bErrs) {
+				bEr = bErrs[i]
+			}
+			diffError(diffPath(path, fmt.Sprintf("errors[%d]", i)), aEr, bEr, o, ancestors, diffs)
+		}
+		return
+	}
+
+	used := make([]bool, len(bErrs))
+	for i, aEr := range aErrs {
+		found := false
+		for j, bEr := range bErrs {
+			if used[j] {
+				continue
+			}
+			d := []string{}
+			diffError("", aEr, bEr, o, ancestors, &d)
+			if len(d) == 0 {
+				used[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			addDiff(diffs, path, fmt.Sprintf("errors[%d]", i), aEr.Error(), nil)
+		}
+	}
+	for j, bEr := range bErrs {
+		if !used[j] {
+			addDiff(diffs, path, fmt.Sprintf("errors[%d]", j), nil, bEr.Error())
+		}
+	}
+}
+
+// joinedErrors returns joined errors of err the same
+// way they are selected when marshaling err into JSON.
+func joinedErrors(err, cause error, errs []error) []error {
+	result := []error{}
+	for _, er := range errs {
+		if er != nil && er != cause && !isSubsumedError(err, er) { //nolint:errorlint,err113
+			result = append(result, er)
+		}
+	}
+	return result
+}
+
+// normalizeDetails converts details to their JSON representation
+// so that details of placeholder errors compare equal.
+func normalizeDetails(details map[string]interface{}, o *equalOptions) map[string]interface{} {
+	result := make(map[string]interface{}, len(details))
+	for key, value := range details {
+		if o.ignoreDetails[key] {
+			continue
+		}
+		data, err := marshalWithoutEscapeHTML(value)
+		if err != nil {
+			result[key] = value
+			continue
+		}
+		var normalized interface{}
+		err = json.Unmarshal(data, &normalized)
+		if err != nil {
+			result[key] = value
+			continue
+		}
+		result[key] = normalized
+	}
+	return result
+}
+
+func frameFunctions(err error) []string {
+	frames := Frames(err)
+	result := make([]string, 0, len(frames))
+	for _, f := range frames {
+		result = append(result, f.Function)
+	}
+	return result
+}
+
+func errorMessage(err error) interface{} {
+	if err == nil {
+		return nil
+	}
+	return err.Error()
+}
@@ -0,0 +1,84 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func equalNewError(msg string) errors.E {
+	return errors.New(msg)
+}
+
+func TestEqualPlaceholder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Name string
+		Err  error
+	}{
+		{"new", errors.New("error")},
+		{"details", errors.WithDetails(errors.New("error"), "int", 42, "map", map[string]int{"a": 1})},
+		{"wrap", errors.Wrap(errors.WithDetails(io.EOF, "key", "value"), "wrap")},
+		{"wrapWith", errors.WrapWith(errors.New("error"), errors.Base("with"))},
+		{"join", errors.Join(errors.New("first"), errors.Wrap(io.EOF, "second"))},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := json.Marshal(tt.Err)
+			require.NoError(t, err)
+			placeholder, errE := errors.UnmarshalJSON(data)
+			require.NoError(t, errE, "% -+#.1v", errE)
+
+			assert.True(t, errors.Equal(tt.Err, placeholder, errors.CompareStackFunctions()), errors.Diff(tt.Err, placeholder, errors.CompareStackFunctions()))
+			assert.True(t, errors.Equal(placeholder, tt.Err))
+			assert.Equal(t, "", errors.Diff(tt.Err, placeholder))
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, errors.Equal(nil, nil))
+	assert.Equal(t, `error: "error" != <nil>`, errors.Diff(errors.New("error"), nil))
+
+	a := errors.Wrap(errors.WithDetails(errors.New("error"), "key", "a", "id", 1), "wrap")
+	b := errors.Wrap(errors.WithDetails(errors.New("other"), "key", "b", "id", 1, "extra", true), "wrap")
+	assert.False(t, errors.Equal(a, b))
+	assert.Equal(t, `cause.message: "error" != "other"
+cause.details[extra]: <nil> != true
+cause.details[key]: "a" != "b"`, errors.Diff(a, b))
+	assert.Equal(t, `cause.message: "error" != "other"`, errors.Diff(a, b, errors.IgnoreDetails("key", "extra")))
+
+	// Stacks are ignored by default.
+	a = errors.New("error")
+	b = equalNewError("error")
+	assert.True(t, errors.Equal(a, b))
+	assert.False(t, errors.Equal(a, b, errors.CompareStackFunctions()))
+	assert.Contains(t, errors.Diff(a, b, errors.CompareStackFunctions()), "stack: ")
+
+	a = errors.Join(errors.Base("first"), errors.Base("second"))
+	b = errors.Join(errors.Base("second"), errors.Base("first"))
+	assert.Equal(t, `message: "first\nsecond" != "second\nfirst"
+errors[0].message: "first" != "second"
+errors[1].message: "second" != "first"`, errors.Diff(a, b))
+	assert.Equal(t, "", errors.Diff(a, b, errors.IgnoreJoinedOrder()))
+	assert.True(t, errors.Equal(a, b, errors.IgnoreJoinedOrder()))
+	assert.False(t, errors.Equal(errors.Wrap(a, "one"), errors.Wrap(b, "two"), errors.IgnoreJoinedOrder()))
+
+	c := errors.Join(errors.Base("second"), errors.Base("third"))
+	assert.Equal(t, `message: "first\nsecond" != "second\nthird"
+errors[0]: "first" != <nil>
+errors[1]: <nil> != "third"`, errors.Diff(a, c, errors.IgnoreJoinedOrder()))
+}
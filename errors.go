@@ -211,6 +211,11 @@ type erisStackTracer interface {
 	StackFrames() []uintptr
 }
 
+// ownError is implemented by all errors made by this package.
+type ownError interface {
+	ownError()
+}
+
 type causer interface {
 	Cause() error
 }
@@ -228,7 +233,8 @@ type detailer interface {
 }
 
 func getExistingStackTrace(err error) []uintptr {
-	for err != nil {
+	var cycle unwrapCycle
+	for err != nil && !cycle.repeated(err) {
 		switch e := err.(type) { //nolint:errorlint
 		case stackTracer:
 			return e.StackTrace()
@@ -275,15 +281,21 @@ func prefixMessage(msg string, prefixes ...string) string {
 // This is a trade-off which consumes more memory but allows one to cheaply
 // call Error multiple times.
 func joinMessages(errs []error) string {
-	// Same implementation as standard library's joinError's Error.
+	// Same implementation as standard library's joinError's Error,
+	// but respecting limits (see SetLimits).
+	l := GetLimits()
 	var b []byte
 	for i, err := range errs {
 		if i > 0 {
 			b = append(b, '\n')
 		}
+		if l.MaxJoined > 0 && i >= l.MaxJoined {
+			b = append(b, moreErrors(len(errs)-i)...)
+			break
+		}
 		b = append(b, err.Error()...)
 	}
-	return string(b)
+	return truncateMessage(string(b), l.MaxMessageLength)
 }
 
 // E interface can be used in as a return type instead of the standard error
@@ -307,12 +319,12 @@ type E interface {
 // New returns an error with the supplied message.
 // New also records the stack trace at the point it was called.
 func New(message string) E {
-	return &fundamentalError{
+	return record(&fundamentalError{
 		msg:       message,
 		stack:     callers(0),
 		details:   nil,
 		detailsMu: new(sync.Mutex),
-	}
+	}, EventNew, true, 0)
 }
 
 // Errorf return an error with the supplied message
@@ -333,35 +345,36 @@ func Errorf(format string, args ...interface{}) E {
 		errs = []error{u.Unwrap()}
 	}
 	if len(errs) > 1 {
-		return &msgJoinedError{
+		return record(&msgJoinedError{
 			errs:      errs,
 			msg:       err.Error(),
 			stack:     callers(0),
 			details:   nil,
 			detailsMu: new(sync.Mutex),
-		}
+		}, EventErrorf, true, 0)
 	} else if len(errs) == 1 {
 		unwrap := errs[0]
 		st := getExistingStackTrace(unwrap)
-		if len(st) == 0 {
+		newStack := len(st) == 0
+		if newStack {
 			st = callers(0)
 		}
 
-		return &msgError{
+		return record(&msgError{
 			err:       unwrap,
 			msg:       err.Error(),
 			stack:     st,
 			details:   nil,
 			detailsMu: new(sync.Mutex),
-		}
+		}, EventErrorf, newStack, 0)
 	}
 
-	return &fundamentalError{
+	return record(&fundamentalError{
 		msg:       err.Error(),
 		stack:     callers(0),
 		details:   nil,
 		detailsMu: new(sync.Mutex),
-	}
+	}, EventErrorf, true, 0)
 }
 
 // fundamentalError is an error that has a message and a stack,
@@ -373,6 +386,8 @@ type fundamentalError struct {
 	detailsMu *sync.Mutex
 }
 
+func (e *fundamentalError) ownError() {}
+
 func (e *fundamentalError) Error() string {
 	if isCalledFromRuntimePanic() {
 		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
@@ -384,8 +399,8 @@ func (e *fundamentalError) Format(s fmt.State, verb rune) {
 	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
 }
 
-func (e fundamentalError) MarshalJSON() ([]byte, error) {
-	return marshalJSONError(&e)
+func (e *fundamentalError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
 }
 
 func (e *fundamentalError) StackTrace() []uintptr {
@@ -411,6 +426,8 @@ type msgError struct {
 	detailsMu *sync.Mutex
 }
 
+func (e *msgError) ownError() {}
+
 func (e *msgError) Error() string {
 	if isCalledFromRuntimePanic() {
 		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
@@ -422,8 +439,8 @@ func (e *msgError) Format(s fmt.State, verb rune) {
 	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
 }
 
-func (e msgError) MarshalJSON() ([]byte, error) {
-	return marshalJSONError(&e)
+func (e *msgError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
 }
 
 func (e *msgError) Unwrap() error {
@@ -454,6 +471,8 @@ type msgJoinedError struct {
 	detailsMu *sync.Mutex
 }
 
+func (e *msgJoinedError) ownError() {}
+
 func (e *msgJoinedError) Error() string {
 	if isCalledFromRuntimePanic() {
 		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
@@ -465,8 +484,8 @@ func (e *msgJoinedError) Format(s fmt.State, verb rune) {
 	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
 }
 
-func (e msgJoinedError) MarshalJSON() ([]byte, error) {
-	return marshalJSONError(&e)
+func (e *msgJoinedError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
 }
 
 func (e *msgJoinedError) Unwrap() []error {
@@ -487,31 +506,32 @@ func (e *msgJoinedError) Details() map[string]interface{} {
 	return e.details
 }
 
-func withStack(err error) E {
+func withStack(err error, kind EventKind) E {
 	e, ok := err.(E) //nolint:errorlint
 	if ok {
 		if len(e.StackTrace()) == 0 {
-			return &noMsgError{
+			return record(&noMsgError{
 				err:       err,
 				stack:     callers(1),
 				details:   nil,
 				detailsMu: new(sync.Mutex),
-			}
+			}, kind, true, 1)
 		}
 		return e
 	}
 
 	st := getExistingStackTrace(err)
-	if len(st) == 0 {
+	newStack := len(st) == 0
+	if newStack {
 		st = callers(1)
 	}
 
-	return &noMsgError{
+	return record(&noMsgError{
 		err:       err,
 		stack:     st,
 		details:   nil,
 		detailsMu: new(sync.Mutex),
-	}
+	}, kind, newStack, 1)
 }
 
 // WithStack annotates err with a stack trace at the point WithStack was called,
@@ -534,7 +554,7 @@ func WithStack(err error) E {
 		return nil
 	}
 
-	return withStack(err)
+	return withStack(err, EventWithStack)
 }
 
 // noMsgError wraps another error and has its
@@ -546,6 +566,8 @@ type noMsgError struct {
 	detailsMu *sync.Mutex
 }
 
+func (e *noMsgError) ownError() {}
+
 func (e *noMsgError) Error() string {
 	if isCalledFromRuntimePanic() {
 		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
@@ -557,8 +579,8 @@ func (e *noMsgError) Format(s fmt.State, verb rune) {
 	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
 }
 
-func (e noMsgError) MarshalJSON() ([]byte, error) {
-	return marshalJSONError(&e)
+func (e *noMsgError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
 }
 
 func (e *noMsgError) Unwrap() error {
@@ -579,6 +601,45 @@ func (e *noMsgError) Details() map[string]interface{} {
 	return e.details
 }
 
+// annotation is embedded in errors which wrap another error and have their
+// own stack and some additional data (e.g., a public message or an exit code),
+// but do not have their own msg.
+type annotation struct {
+	err       error
+	stack     []uintptr
+	details   map[string]interface{}
+	detailsMu *sync.Mutex
+}
+
+func newAnnotation(err error, stack []uintptr) annotation {
+	return annotation{
+		err:       err,
+		stack:     stack,
+		details:   nil,
+		detailsMu: new(sync.Mutex),
+	}
+}
+
+func (e *annotation) ownError() {}
+
+func (e *annotation) Unwrap() error {
+	return e.err
+}
+
+func (e *annotation) StackTrace() []uintptr {
+	return e.stack
+}
+
+func (e *annotation) Details() map[string]interface{} {
+	e.detailsMu.Lock()
+	defer e.detailsMu.Unlock()
+
+	if e.details == nil {
+		e.details = make(map[string]interface{})
+	}
+	return e.details
+}
+
 // Wrap returns an error annotating err with a stack trace
 // at the point Wrap is called, and the supplied message.
 // Wrapping is done even if err already has a stack trace.
@@ -594,13 +655,13 @@ func Wrap(err error, message string) E {
 		return nil
 	}
 
-	return &causeError{
+	return record(&causeError{
 		err:       err,
 		msg:       message,
 		stack:     callers(0),
 		details:   nil,
 		detailsMu: new(sync.Mutex),
-	}
+	}, EventWrap, true, 0)
 }
 
 // Wrapf returns an error annotating err with a stack trace
@@ -621,13 +682,13 @@ func Wrapf(err error, format string, args ...interface{}) E {
 		return nil
 	}
 
-	return &causeError{
+	return record(&causeError{
 		err:       err,
 		msg:       fmt.Sprintf(format, args...),
 		stack:     callers(0),
 		details:   nil,
 		detailsMu: new(sync.Mutex),
-	}
+	}, EventWrapf, true, 0)
 }
 
 // causeError records another error as a causeError
@@ -640,6 +701,8 @@ type causeError struct {
 	detailsMu *sync.Mutex
 }
 
+func (e *causeError) ownError() {}
+
 func (e *causeError) Error() string {
 	if isCalledFromRuntimePanic() {
 		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
@@ -651,8 +714,8 @@ func (e *causeError) Format(s fmt.State, verb rune) {
 	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
 }
 
-func (e causeError) MarshalJSON() ([]byte, error) {
-	return marshalJSONError(&e)
+func (e *causeError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
 }
 
 func (e *causeError) Unwrap() error {
@@ -677,19 +740,20 @@ func (e *causeError) Details() map[string]interface{} {
 	return e.details
 }
 
-func withMessage(err error, prefix ...string) E {
+func withMessage(err error, kind EventKind, prefix ...string) E {
 	st := getExistingStackTrace(err)
-	if len(st) == 0 {
+	newStack := len(st) == 0
+	if newStack {
 		st = callers(1)
 	}
 
-	return &msgError{
+	return record(&msgError{
 		err:       err,
 		msg:       prefixMessage(err.Error(), prefix...),
 		stack:     st,
 		details:   nil,
 		detailsMu: new(sync.Mutex),
-	}
+	}, kind, newStack, 1)
 }
 
 // WithMessage annotates err with a prefix message or messages.
@@ -706,7 +770,7 @@ func WithMessage(err error, prefix ...string) E {
 		return nil
 	}
 
-	return withMessage(err, prefix...)
+	return withMessage(err, EventWithMessage, prefix...)
 }
 
 // WithMessagef annotates err with a prefix message
@@ -725,7 +789,7 @@ func WithMessagef(err error, format string, args ...interface{}) E {
 		return nil
 	}
 
-	return withMessage(err, fmt.Sprintf(format, args...))
+	return withMessage(err, EventWithMessagef, fmt.Sprintf(format, args...))
 }
 
 // Cause returns the result of calling the Cause method on err, if err's
@@ -735,7 +799,8 @@ func WithMessagef(err error, format string, args ...interface{}) E {
 // Unwrapping stops if it encounters an error with
 // Unwrap() method returning multiple errors.
 func Cause(err error) error {
-	for err != nil {
+	var cycle unwrapCycle
+	for err != nil && !cycle.repeated(err) {
 		c, ok := err.(causer)
 		if ok {
 			cause := c.Cause()
@@ -749,7 +814,7 @@ func Cause(err error) error {
 		}
 		err = Unwrap(err)
 	}
-	return err
+	return nil
 }
 
 // Unjoin returns the result of calling the Unwrap method on err, if err's
@@ -759,7 +824,8 @@ func Cause(err error) error {
 // Unwrapping stops if it encounters an error with the Cause
 // method returning error.
 func Unjoin(err error) []error {
-	for err != nil {
+	var cycle unwrapCycle
+	for err != nil && !cycle.repeated(err) {
 		e, ok := err.(unwrapperJoined)
 		if ok {
 			errs := e.Unwrap()
@@ -786,7 +852,8 @@ func Unjoin(err error) []error {
 //
 // You can modify returned map to modify err's details.
 func Details(err error) map[string]interface{} {
-	for err != nil {
+	var cycle unwrapCycle
+	for err != nil && !cycle.repeated(err) {
 		dd := detailsOf(err)
 		if dd != nil {
 			return dd
@@ -825,7 +892,8 @@ func detailsOf(err error) map[string]interface{} {
 // multiple errors.
 func AllDetails(err error) map[string]interface{} {
 	res := make(map[string]interface{})
-	for err != nil {
+	var cycle unwrapCycle
+	for err != nil && !cycle.repeated(err) {
 		for key, value := range detailsOf(err) {
 			if _, ok := res[key]; !ok {
 				res[key] = value
@@ -852,7 +920,8 @@ func allDetailsUntilCauseOrJoined(err error) (res map[string]interface{}, cause
 	cause = nil
 	errs = nil
 
-	for err != nil {
+	var cycle unwrapCycle
+	for err != nil && !cycle.repeated(err) {
 		for key, value := range detailsOf(err) {
 			if _, ok := res[key]; !ok {
 				res[key] = value
@@ -883,7 +952,8 @@ func causeOrJoined(err error) (cause error, errs []error) { //nolint:revive,nona
 	cause = nil
 	errs = nil
 
-	for err != nil {
+	var cycle unwrapCycle
+	for err != nil && !cycle.repeated(err) {
 		c, ok := err.(causer)
 		if ok {
 			cause = c.Cause()
@@ -945,16 +1015,17 @@ func WithDetails(err error, kv ...interface{}) E {
 	// We do not have to check for type E explicitly because E implements stackTracer
 	// so getExistingStackTrace returns its stack trace.
 	st := getExistingStackTrace(err)
-	if len(st) == 0 {
+	newStack := len(st) == 0
+	if newStack {
 		st = callers(0)
 	}
 
-	return &noMsgError{
+	return record(&noMsgError{
 		err:       err,
 		stack:     st,
 		details:   initMap,
 		detailsMu: new(sync.Mutex),
-	}
+	}, EventWithDetails, newStack, 0)
 }
 
 // Join returns an error that wraps the given errors.
@@ -982,16 +1053,16 @@ func Join(errs ...error) E {
 	if len(nonNilErrs) == 0 {
 		return nil
 	} else if len(nonNilErrs) == 1 {
-		return withStack(nonNilErrs[0])
+		return withStack(nonNilErrs[0], EventJoin)
 	}
 
-	return &msgJoinedError{
+	return record(&msgJoinedError{
 		errs:      nonNilErrs,
 		msg:       joinMessages(nonNilErrs),
 		stack:     callers(0),
 		details:   nil,
 		detailsMu: new(sync.Mutex),
-	}
+	}, EventJoin, true, 0)
 }
 
 // wrapError joins two errors (err and with), making err the cause of with.
@@ -1003,6 +1074,8 @@ type wrapError struct {
 	detailsMu *sync.Mutex
 }
 
+func (e *wrapError) ownError() {}
+
 func (e *wrapError) Error() string {
 	if isCalledFromRuntimePanic() {
 		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
@@ -1014,8 +1087,8 @@ func (e *wrapError) Format(s fmt.State, verb rune) {
 	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
 }
 
-func (e wrapError) MarshalJSON() ([]byte, error) {
-	return marshalJSONError(&e)
+func (e *wrapError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
 }
 
 func (e *wrapError) Unwrap() []error {
@@ -1071,17 +1144,18 @@ func WrapWith(err, with error) E {
 	}
 
 	st := getExistingStackTrace(with)
-	if len(st) == 0 {
+	newStack := len(st) == 0
+	if newStack {
 		st = callers(0)
 	}
 
-	return &wrapError{
+	return record(&wrapError{
 		err:       err,
 		with:      with,
 		stack:     st,
 		details:   nil,
 		detailsMu: new(sync.Mutex),
-	}
+	}, EventWrapWith, newStack, 0)
 }
 
 // Prefix annotates err with a prefix message or messages of prefix errors,
@@ -1118,21 +1192,22 @@ func Prefix(err error, prefix ...error) E {
 	}
 
 	if len(nonNilErrs) == 0 {
-		return withStack(err)
+		return withStack(err, EventPrefix)
 	}
 
 	st := getExistingStackTrace(err)
-	if len(st) == 0 {
+	newStack := len(st) == 0
+	if newStack {
 		st = callers(0)
 	}
 
 	nonNilErrs = append(nonNilErrs, err)
 
-	return &msgJoinedError{
+	return record(&msgJoinedError{
 		errs:      nonNilErrs,
 		msg:       prefixMessage(err.Error(), prefixes...),
 		stack:     st,
 		details:   nil,
 		detailsMu: new(sync.Mutex),
-	}
+	}, EventPrefix, newStack, 0)
 }
@@ -0,0 +1,359 @@
+// Package errorstest provides helpers for testing errors made with
+// the gitlab.com/tozd/go/errors package (and other errors).
+//
+// Helpers assert on the tree of errors, details, causes and joined errors,
+// and stack traces. They also support comparing formatted errors and errors
+// marshaled as JSON against golden files, with machine-specific parts
+// (e.g., paths and line numbers) normalized.
+//
+// Helpers report failures using t.Errorf and return true if the
+// assertion holds, so they can be used with the standard testing package.
+package errorstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// UpdateEnv is the name of the environment variable which, when set
+// to a non-empty value, makes golden helpers update golden files
+// instead of comparing against them.
+const UpdateEnv = "ERRORSTEST_UPDATE"
+
+type stackTracer interface {
+	StackTrace() []uintptr
+}
+
+const errorFormat = "% -+#.1v"
+
+// Is asserts that errors.Is(err, target) is true.
+func Is(t testing.TB, err, target error) bool {
+	t.Helper()
+
+	if !errors.Is(err, target) {
+		t.Errorf("error does not match target %q:\n"+errorFormat, target, errors.Formatter{Error: err})
+		return false
+	}
+	return true
+}
+
+// NotIs asserts that errors.Is(err, target) is false.
+func NotIs(t testing.TB, err, target error) bool {
+	t.Helper()
+
+	if errors.Is(err, target) {
+		t.Errorf("error matches target %q:\n"+errorFormat, target, errors.Formatter{Error: err})
+		return false
+	}
+	return true
+}
+
+// As asserts that errors.As(err, target) is true.
+func As(t testing.TB, err error, target interface{}) bool {
+	t.Helper()
+
+	if !errors.As(err, target) {
+		t.Errorf("error does not match target type %T:\n"+errorFormat, target, errors.Formatter{Error: err})
+		return false
+	}
+	return true
+}
+
+// HasDetail asserts that errors.AllDetails(err) contains key.
+// It returns the value of the detail.
+func HasDetail(t testing.TB, err error, key string) (interface{}, bool) {
+	t.Helper()
+
+	value, ok := errors.AllDetails(err)[key]
+	if !ok {
+		t.Errorf("error does not have detail %q:\n"+errorFormat, key, errors.Formatter{Error: err})
+		return nil, false
+	}
+	return value, true
+}
+
+// DetailEqual asserts that errors.AllDetails(err) contains key
+// with value deeply equal to expected.
+func DetailEqual(t testing.TB, err error, key string, expected interface{}) bool {
+	t.Helper()
+
+	value, ok := HasDetail(t, err, key)
+	if !ok {
+		return false
+	}
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("error detail %q is %#v, expected %#v", key, value, expected)
+		return false
+	}
+	return true
+}
+
+// CauseIs asserts that err has a cause (as returned by errors.Cause)
+// and that errors.Is(cause, target) is true.
+func CauseIs(t testing.TB, err, target error) bool {
+	t.Helper()
+
+	cause := errors.Cause(err)
+	if cause == nil {
+		t.Errorf("error does not have a cause:\n"+errorFormat, errors.Formatter{Error: err})
+		return false
+	}
+	if !errors.Is(cause, target) {
+		t.Errorf("error cause does not match target %q:\n"+errorFormat, target, errors.Formatter{Error: err})
+		return false
+	}
+	return true
+}
+
+// NoCause asserts that err does not have a cause (as returned by errors.Cause).
+func NoCause(t testing.TB, err error) bool {
+	t.Helper()
+
+	if errors.Cause(err) != nil {
+		t.Errorf("error has a cause:\n"+errorFormat, errors.Formatter{Error: err})
+		return false
+	}
+	return true
+}
+
+// Joined asserts that err joins (as returned by errors.Unjoin) exactly
+// the expected number of errors. It returns joined errors.
+func Joined(t testing.TB, err error, expected int) ([]error, bool) {
+	t.Helper()
+
+	errs := errors.Unjoin(err)
+	if len(errs) != expected {
+		t.Errorf("error joins %d errors, expected %d:\n"+errorFormat, len(errs), expected, errors.Formatter{Error: err})
+		return errs, false
+	}
+	return errs, true
+}
+
+// HasStack asserts that err has a stack trace.
+// It returns the stack trace.
+func HasStack(t testing.TB, err error) ([]uintptr, bool) {
+	t.Helper()
+
+	var st stackTracer
+	if !errors.As(err, &st) || len(st.StackTrace()) == 0 {
+		t.Errorf("error does not have a stack trace:\n"+errorFormat, errors.Formatter{Error: err})
+		return nil, false
+	}
+	return st.StackTrace(), true
+}
+
+// StackContains asserts that err has a stack trace which includes
+// a frame of the function with the full name function
+// (e.g., "gitlab.com/tozd/go/errors_test.TestFoo").
+func StackContains(t testing.TB, err error, function string) bool {
+	t.Helper()
+
+	st, ok := HasStack(t, err)
+	if !ok {
+		return false
+	}
+	frames := runtime.CallersFrames(st)
+	for {
+		frame, more := frames.Next()
+		if frame.Function == function {
+			return true
+		}
+		if !more {
+			break
+		}
+	}
+	t.Errorf("error stack trace does not include function %q:\n"+errorFormat, function, errors.Formatter{Error: err})
+	return false
+}
+
+var (
+	locationRegexp      = regexp.MustCompile(`(?m)^([ \t]+)(?:\S*/)?([^/\s]+):\d+(?: \+0x[0-9a-f]+)?$`)
+	runtimeFrameRegexp  = regexp.MustCompile(`(?m)^[ \t]*(?:runtime|testing)\.\S+\n[ \t]+\S+:\d+(?: \+0x[0-9a-f]+)?\n`)
+	jsonFrameFieldNames = []string{"name", "file", "line"}
+)
+
+func isRuntimeFunction(name string) bool {
+	return strings.HasPrefix(name, "runtime.") || strings.HasPrefix(name, "testing.")
+}
+
+// NormalizeText normalizes text of a formatted error so that it does not
+// depend on the machine nor the version of Go.
+//
+// Stack frames of the runtime and testing packages are removed, paths are
+// replaced with file names, and line numbers and program counters are
+// replaced with "N".
+func NormalizeText(text string) string {
+	text = runtimeFrameRegexp.ReplaceAllString(text, "")
+	return locationRegexp.ReplaceAllString(text, "${1}${2}:N")
+}
+
+// NormalizeJSON normalizes an error marshaled as JSON so that it does not
+// depend on the machine nor the version of Go.
+//
+// Stack frames of the runtime and testing packages are removed, paths are
+// replaced with file names, and line numbers are replaced with 0.
+// The result is indented.
+func NormalizeJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	v = normalizeJSONValue(v)
+	data, err = json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return append(data, '\n'), nil
+}
+
+// StableFrame rewrites frame so that it does not depend on the machine nor
+// the version of Go. Frames of the runtime and testing packages are omitted,
+// the path is replaced with the file name, and the line number with 0.
+//
+// Use it as RewriteFrame of errors.Formatter or errors.FormatOptions to
+// compare formatted and marshaled errors byte-for-byte, without normalizing them.
+func StableFrame(frame runtime.Frame) (runtime.Frame, bool) {
+	if isRuntimeFunction(frame.Function) {
+		return frame, false
+	}
+	return runtime.Frame{ //nolint:exhaustruct
+		Function: frame.Function,
+		File:     path.Base(frame.File),
+		Line:     0,
+	}, true
+}
+
+func isJSONFrame(v interface{}) bool {
+	frame, ok := v.(map[string]interface{})
+	if !ok || len(frame) == 0 {
+		return false
+	}
+	for key := range frame {
+		known := false
+		for _, name := range jsonFrameFieldNames {
+			if key == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeJSONValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, val := range value {
+			value[key] = normalizeJSONValue(val)
+		}
+		return value
+	case []interface{}:
+		if len(value) > 0 && isJSONFrame(value[0]) {
+			frames := []interface{}{}
+			for _, f := range value {
+				frame, ok := f.(map[string]interface{})
+				if !ok || !isJSONFrame(frame) {
+					frames = append(frames, normalizeJSONValue(f))
+					continue
+				}
+				name, _ := frame["name"].(string)
+				if isRuntimeFunction(name) {
+					continue
+				}
+				if file, ok := frame["file"].(string); ok {
+					frame["file"] = path.Base(file)
+				}
+				if _, ok := frame["line"]; ok {
+					frame["line"] = 0
+				}
+				frames = append(frames, frame)
+			}
+			return frames
+		}
+		for i, val := range value {
+			value[i] = normalizeJSONValue(val)
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+// compareGolden compares actual with the content of the golden file
+// or updates the golden file, if requested.
+func compareGolden(t testing.TB, filename string, actual []byte) bool {
+	t.Helper()
+
+	if os.Getenv(UpdateEnv) != "" {
+		err := os.MkdirAll(filepath.Dir(filename), 0o755) //nolint:mnd
+		if err != nil {
+			t.Errorf("cannot create directory for golden file %q: %v", filename, err)
+			return false
+		}
+		err = os.WriteFile(filename, actual, 0o644) //nolint:gosec,mnd
+		if err != nil {
+			t.Errorf("cannot write golden file %q: %v", filename, err)
+			return false
+		}
+		return true
+	}
+
+	expected, err := os.ReadFile(filename)
+	if err != nil {
+		t.Errorf("cannot read golden file %q (set %s environment variable to create it): %v", filename, UpdateEnv, err)
+		return false
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("golden file %q does not match:\nexpected:\n%s\nactual:\n%s", filename, expected, actual)
+		return false
+	}
+	return true
+}
+
+// GoldenText asserts that err formatted using errors.Formatter and format
+// (e.g., "% -+#.1v") and normalized using NormalizeText matches the content
+// of the golden file.
+//
+// If the environment variable named by UpdateEnv is set, the golden file
+// is updated instead.
+func GoldenText(t testing.TB, err error, format, filename string) bool {
+	t.Helper()
+
+	return compareGolden(t, filename, []byte(NormalizeText(fmt.Sprintf(format, errors.Formatter{Error: err}))))
+}
+
+// GoldenJSON asserts that err marshaled as JSON using errors.Formatter
+// and normalized using NormalizeJSON matches the content of the golden file.
+//
+// If the environment variable named by UpdateEnv is set, the golden file
+// is updated instead.
+func GoldenJSON(t testing.TB, err error, filename string) bool {
+	t.Helper()
+
+	data, e := json.Marshal(errors.Formatter{Error: err})
+	if e != nil {
+		t.Errorf("cannot marshal error: %v", e)
+		return false
+	}
+	data, e = NormalizeJSON(data)
+	if e != nil {
+		t.Errorf("cannot normalize JSON: %v", e)
+		return false
+	}
+	return compareGolden(t, filename, data)
+}
@@ -0,0 +1,343 @@
+package errorstest_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/errors/errorstest"
+)
+
+// recordingT records failures instead of failing the test.
+type recordingT struct {
+	testing.TB
+
+	failures []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func expectResult(t *testing.T, rt *recordingT, ok, expected bool) {
+	t.Helper()
+
+	if ok != expected {
+		t.Errorf("expected %t, got %t", expected, ok)
+	}
+	if expected && len(rt.failures) > 0 {
+		t.Errorf("unexpected failures: %v", rt.failures)
+	}
+	if !expected && len(rt.failures) == 0 {
+		t.Errorf("expected a failure")
+	}
+}
+
+var errBase = errors.Base("base")
+
+func makeError() errors.E {
+	return errors.WithDetails(errBase, "key", "value")
+}
+
+func TestAssertions(t *testing.T) {
+	t.Parallel()
+
+	err := makeError()
+	wrapped := errors.Wrap(err, "wrapped")
+	joined := errors.Join(errors.Base("first"), errors.Base("second"))
+
+	tests := []struct {
+		name     string
+		assert   func(testing.TB) bool
+		expected bool
+	}{
+		{"Is", func(t testing.TB) bool { return errorstest.Is(t, err, errBase) }, true},
+		{"IsFail", func(t testing.TB) bool { return errorstest.Is(t, err, os.ErrNotExist) }, false},
+		{"NotIs", func(t testing.TB) bool { return errorstest.NotIs(t, err, os.ErrNotExist) }, true},
+		{"NotIsFail", func(t testing.TB) bool { return errorstest.NotIs(t, err, errBase) }, false},
+		{"As", func(t testing.TB) bool {
+			var e errors.E
+			return errorstest.As(t, err, &e)
+		}, true},
+		{"AsFail", func(t testing.TB) bool {
+			var e *fs.PathError
+			return errorstest.As(t, err, &e)
+		}, false},
+		{"HasDetail", func(t testing.TB) bool {
+			_, ok := errorstest.HasDetail(t, err, "key")
+			return ok
+		}, true},
+		{"HasDetailFail", func(t testing.TB) bool {
+			_, ok := errorstest.HasDetail(t, err, "missing")
+			return ok
+		}, false},
+		{"DetailEqual", func(t testing.TB) bool { return errorstest.DetailEqual(t, err, "key", "value") }, true},
+		{"DetailEqualFail", func(t testing.TB) bool { return errorstest.DetailEqual(t, err, "key", "other") }, false},
+		{"CauseIs", func(t testing.TB) bool { return errorstest.CauseIs(t, wrapped, errBase) }, true},
+		{"CauseIsFail", func(t testing.TB) bool { return errorstest.CauseIs(t, wrapped, os.ErrNotExist) }, false},
+		{"CauseIsNoCause", func(t testing.TB) bool { return errorstest.CauseIs(t, err, errBase) }, false},
+		{"NoCause", func(t testing.TB) bool { return errorstest.NoCause(t, err) }, true},
+		{"NoCauseFail", func(t testing.TB) bool { return errorstest.NoCause(t, wrapped) }, false},
+		{"Joined", func(t testing.TB) bool {
+			_, ok := errorstest.Joined(t, joined, 2)
+			return ok
+		}, true},
+		{"JoinedFail", func(t testing.TB) bool {
+			_, ok := errorstest.Joined(t, err, 2)
+			return ok
+		}, false},
+		{"HasStack", func(t testing.TB) bool {
+			_, ok := errorstest.HasStack(t, err)
+			return ok
+		}, true},
+		{"HasStackFail", func(t testing.TB) bool {
+			_, ok := errorstest.HasStack(t, errBase)
+			return ok
+		}, false},
+		{"StackContains", func(t testing.TB) bool {
+			return errorstest.StackContains(t, err, "gitlab.com/tozd/go/errors/errorstest_test.makeError")
+		}, true},
+		{"StackContainsFail", func(t testing.TB) bool {
+			return errorstest.StackContains(t, err, "gitlab.com/tozd/go/errors/errorstest_test.missing")
+		}, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rt := &recordingT{TB: t}
+			expectResult(t, rt, tt.assert(rt), tt.expected)
+		})
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	t.Parallel()
+
+	text := "error\n" +
+		"stack trace (most recent call first):\n" +
+		"gitlab.com/tozd/go/errors/errorstest_test.makeError\n" +
+		"\t/home/user/errors/errorstest/errorstest_test.go:43\n" +
+		"testing.tRunner\n" +
+		"\t/usr/local/go/src/testing/testing.go:1595\n" +
+		"main.main\n" +
+		"\t_testmain.go:47 +0x1d\n" +
+		"runtime.goexit\n" +
+		"\t/usr/local/go/src/runtime/asm_amd64.s:1650\n"
+
+	expected := "error\n" +
+		"stack trace (most recent call first):\n" +
+		"gitlab.com/tozd/go/errors/errorstest_test.makeError\n" +
+		"\terrorstest_test.go:N\n" +
+		"main.main\n" +
+		"\t_testmain.go:N\n"
+
+	if actual := errorstest.NormalizeText(text); actual != expected {
+		t.Errorf("unexpected normalized text:\n%s", actual)
+	}
+}
+
+func TestNormalizeJSON(t *testing.T) {
+	t.Parallel()
+
+	data := `{"error":"error","key":[{"name":"value"}],"stack":[` +
+		`{"name":"main.run","file":"/home/user/main.go","line":12},` +
+		`{"name":"runtime.main","file":"/usr/local/go/src/runtime/proc.go","line":267}` +
+		`],"cause":{"error":"cause","stack":[{"name":"main.main","file":"/home/user/main.go","line":20}]}}`
+
+	expected := `{
+  "cause": {
+    "error": "cause",
+    "stack": [
+      {
+        "file": "main.go",
+        "line": 0,
+        "name": "main.main"
+      }
+    ]
+  },
+  "error": "error",
+  "key": [
+    {
+      "name": "value"
+    }
+  ],
+  "stack": [
+    {
+      "file": "main.go",
+      "line": 0,
+      "name": "main.run"
+    }
+  ]
+}
+`
+
+	actual, err := errorstest.NormalizeJSON([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != expected {
+		t.Errorf("unexpected normalized JSON:\n%s", actual)
+	}
+}
+
+func TestGolden(t *testing.T) { //nolint:paralleltest
+	// We cannot use t.Parallel with t.Setenv.
+
+	dir := t.TempDir()
+	err := errors.Wrap(makeError(), "wrapped")
+	textFile := filepath.Join(dir, "error.txt")
+	jsonFile := filepath.Join(dir, "error.json")
+
+	rt := &recordingT{TB: t}
+	expectResult(t, rt, errorstest.GoldenText(rt, err, "% -+#.1v", textFile), false)
+
+	t.Setenv(errorstest.UpdateEnv, "1")
+	rt = &recordingT{TB: t}
+	expectResult(t, rt, errorstest.GoldenText(rt, err, "% -+#.1v", textFile), true)
+	expectResult(t, rt, errorstest.GoldenJSON(rt, err, jsonFile), true)
+
+	t.Setenv(errorstest.UpdateEnv, "")
+	rt = &recordingT{TB: t}
+	expectResult(t, rt, errorstest.GoldenText(rt, err, "% -+#.1v", textFile), true)
+	expectResult(t, rt, errorstest.GoldenJSON(rt, err, jsonFile), true)
+
+	text, e := os.ReadFile(textFile)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !strings.Contains(string(text), "\terrorstest_test.go:N\n") || strings.Contains(string(text), "testing.tRunner") {
+		t.Errorf("golden file not normalized:\n%s", text)
+	}
+
+	rt = &recordingT{TB: t}
+	expectResult(t, rt, errorstest.GoldenText(rt, errors.Wrap(makeError(), "other"), "% -+#.1v", textFile), false)
+	rt = &recordingT{TB: t}
+	expectResult(t, rt, errorstest.GoldenJSON(rt, errors.Wrap(makeError(), "other"), jsonFile), false)
+}
+
+func makeNestedError() errors.E {
+	return errors.Wrap(makeError(), "wrapped")
+}
+
+func TestNormalizeError(t *testing.T) {
+	t.Parallel()
+
+	err := makeNestedError()
+
+	expected := "wrapped\n" +
+		"stack trace (most recent call first):\n" +
+		"gitlab.com/tozd/go/errors/errorstest_test.makeNestedError\n" +
+		"\terrorstest_test.go:N\n" +
+		"gitlab.com/tozd/go/errors/errorstest_test.TestNormalizeError\n" +
+		"\terrorstest_test.go:N\n" +
+		"\n" +
+		"the above error was caused by the following error:\n" +
+		"\n" +
+		"base\n" +
+		"key=value\n" +
+		"stack trace (most recent call first):\n" +
+		"gitlab.com/tozd/go/errors/errorstest_test.makeError\n" +
+		"\terrorstest_test.go:N\n" +
+		"gitlab.com/tozd/go/errors/errorstest_test.makeNestedError\n" +
+		"\terrorstest_test.go:N\n" +
+		"gitlab.com/tozd/go/errors/errorstest_test.TestNormalizeError\n" +
+		"\terrorstest_test.go:N\n"
+	if actual := errorstest.NormalizeText(fmt.Sprintf("% -+#.1v", err)); actual != expected {
+		t.Errorf("unexpected formatted error:\n%s", actual)
+	}
+
+	data, e := json.Marshal(err)
+	if e != nil {
+		t.Fatal(e)
+	}
+	normalized, e := errorstest.NormalizeJSON(data)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if strings.Contains(string(normalized), "testing.tRunner") || !strings.Contains(string(normalized), `"file": "errorstest_test.go",`+"\n"+`        "line": 0`) {
+		t.Errorf("unexpected normalized JSON:\n%s", normalized)
+	}
+
+	// Placeholder errors are normalized the same.
+	placeholder, errE := errors.UnmarshalJSON(data)
+	if errE != nil {
+		t.Fatal(errE)
+	}
+	if actual := errorstest.NormalizeText(fmt.Sprintf("% -+#.1v", placeholder)); actual != expected {
+		t.Errorf("unexpected formatted placeholder error:\n%s", actual)
+	}
+	data, e = json.Marshal(placeholder)
+	if e != nil {
+		t.Fatal(e)
+	}
+	normalizedPlaceholder, e := errorstest.NormalizeJSON(data)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if string(normalizedPlaceholder) != string(normalized) {
+		t.Errorf("unexpected normalized placeholder JSON:\n%s", normalizedPlaceholder)
+	}
+}
+
+func TestStableFrame(t *testing.T) {
+	t.Parallel()
+
+	err := makeNestedError()
+
+	expected := "wrapped\n" +
+		"stack trace (most recent call first):\n" +
+		"gitlab.com/tozd/go/errors/errorstest_test.makeNestedError\n" +
+		"\terrorstest_test.go:0\n" +
+		"gitlab.com/tozd/go/errors/errorstest_test.TestStableFrame\n" +
+		"\terrorstest_test.go:0\n" +
+		"\n" +
+		"the above error was caused by the following error:\n" +
+		"\n" +
+		"base\n" +
+		"key=value\n" +
+		"stack trace (most recent call first):\n" +
+		"gitlab.com/tozd/go/errors/errorstest_test.makeError\n" +
+		"\terrorstest_test.go:0\n" +
+		"gitlab.com/tozd/go/errors/errorstest_test.makeNestedError\n" +
+		"\terrorstest_test.go:0\n" +
+		"gitlab.com/tozd/go/errors/errorstest_test.TestStableFrame\n" +
+		"\terrorstest_test.go:0\n"
+	if actual := fmt.Sprintf("% -+#.1v", errors.Formatter{Error: err, RewriteFrame: errorstest.StableFrame}); actual != expected {
+		t.Errorf("unexpected formatted error:\n%s", actual)
+	}
+
+	data, e := json.Marshal(errors.Formatter{Error: err, RewriteFrame: errorstest.StableFrame})
+	if e != nil {
+		t.Fatal(e)
+	}
+	expectedJSON := `{"cause":{"error":"base","key":"value","stack":[` +
+		`{"name":"gitlab.com/tozd/go/errors/errorstest_test.makeError","file":"errorstest_test.go"},` +
+		`{"name":"gitlab.com/tozd/go/errors/errorstest_test.makeNestedError","file":"errorstest_test.go"},` +
+		`{"name":"gitlab.com/tozd/go/errors/errorstest_test.TestStableFrame","file":"errorstest_test.go"}]},` +
+		`"error":"wrapped","stack":[` +
+		`{"name":"gitlab.com/tozd/go/errors/errorstest_test.makeNestedError","file":"errorstest_test.go"},` +
+		`{"name":"gitlab.com/tozd/go/errors/errorstest_test.TestStableFrame","file":"errorstest_test.go"}]}`
+	if string(data) != expectedJSON {
+		t.Errorf("unexpected JSON:\n%s", data)
+	}
+
+	// Placeholder errors are rewritten the same.
+	placeholder, errE := errors.UnmarshalJSON(data)
+	if errE != nil {
+		t.Fatal(errE)
+	}
+	if actual := errors.Sprint(placeholder, errors.FormatOptions{Details: true, Stack: true, Help: true, Spacing: true, Mode: errors.FormatRecursive, RewriteFrame: errorstest.StableFrame}); actual != expected {
+		t.Errorf("unexpected formatted placeholder error:\n%s", actual)
+	}
+}
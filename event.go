@@ -0,0 +1,130 @@
+package errors
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// EventKind is the kind of the function which created an error.
+type EventKind string
+
+// Kinds of functions which create errors.
+const (
+	EventNew               EventKind = "New"
+	EventErrorf            EventKind = "Errorf"
+	EventWrap              EventKind = "Wrap"
+	EventWrapf             EventKind = "Wrapf"
+	EventWrapWith          EventKind = "WrapWith"
+	EventJoin              EventKind = "Join"
+	EventPrefix            EventKind = "Prefix"
+	EventWithStack         EventKind = "WithStack"
+	EventWithDetails       EventKind = "WithDetails"
+	EventWithMessage       EventKind = "WithMessage"
+	EventWithMessagef      EventKind = "WithMessagef"
+	EventWithPublicMessage EventKind = "WithPublicMessage"
+	EventWithExitCode      EventKind = "WithExitCode"
+	EventTrace             EventKind = "Trace"
+	EventRethrow           EventKind = "Rethrow"
+)
+
+// Event describes how an error was created.
+type Event struct {
+	// Kind of the function which created the error.
+	Kind EventKind
+
+	// NewStack is true if a new stack trace was recorded for the error.
+	// It is false if the error reuses the stack trace of the error it wraps.
+	NewStack bool
+
+	// PC is the program counter of the call site of the function
+	// which created the error. Use Frame to resolve it.
+	PC uintptr
+}
+
+// Frame returns the stack frame of the call site of the function
+// which created the error.
+func (e Event) Frame() runtime.Frame {
+	frame, _ := runtime.CallersFrames([]uintptr{e.PC}).Next()
+	return frame
+}
+
+type createHook struct {
+	fn func(E, Event)
+}
+
+var (
+	// createHooks contains []*createHook. It is replaced (and never modified
+	// in place) when hooks are registered or unregistered, so that it can
+	// be read without locking.
+	createHooks   atomic.Value
+	createHooksMu sync.Mutex
+)
+
+// OnCreate registers fn to be called every time New, Errorf, Wrap, WithStack,
+// WithDetails, or any other function of this package creates a new error.
+// Functions which return an existing error as-is (e.g., WithStack called on
+// an error which already has a stack trace) do not call fn.
+// It returns a function which unregisters fn.
+//
+// Hooks are called synchronously in the goroutine creating the error, in the
+// order in which they were registered. They should be fast and must not create
+// errors using this package themselves, as that would call hooks again.
+//
+// Use OnCreate to count errors in metrics, add events to tracing spans,
+// or for debugging. When no hooks are registered, the cost is negligible.
+func OnCreate(fn func(E, Event)) func() {
+	hook := &createHook{fn}
+
+	createHooksMu.Lock()
+	defer createHooksMu.Unlock()
+
+	hooks, _ := createHooks.Load().([]*createHook)
+	newHooks := make([]*createHook, 0, len(hooks)+1)
+	newHooks = append(newHooks, hooks...)
+	newHooks = append(newHooks, hook)
+	createHooks.Store(newHooks)
+
+	return func() {
+		createHooksMu.Lock()
+		defer createHooksMu.Unlock()
+
+		hooks, _ := createHooks.Load().([]*createHook)
+		newHooks := make([]*createHook, 0, len(hooks))
+		for _, h := range hooks {
+			if h != hook {
+				newHooks = append(newHooks, h)
+			}
+		}
+		createHooks.Store(newHooks)
+	}
+}
+
+// record records a newly created err in profiles and calls registered
+// hooks, if any, and returns err. extraSkip is the number of additional
+// frames to skip between the function creating the error and the
+// exported function called by the user.
+func record(err E, kind EventKind, newStack bool, extraSkip int) E {
+	if atomic.LoadInt32(&profilesEnabled) != 0 {
+		recordProfiles(err, extraSkip)
+	}
+
+	hooks, _ := createHooks.Load().([]*createHook)
+	if len(hooks) == 0 {
+		return err
+	}
+
+	var pcs [1]uintptr
+	// Skip runtime.Callers, record, and the function which created the error.
+	runtime.Callers(3+extraSkip, pcs[:]) //nolint:mnd
+	event := Event{
+		Kind:     kind,
+		NewStack: newStack,
+		PC:       pcs[0],
+	}
+	for _, hook := range hooks {
+		hook.fn(err, event)
+	}
+
+	return err
+}
@@ -0,0 +1,94 @@
+package errors_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func TestOnCreate(t *testing.T) { //nolint:paralleltest
+	// We cannot use t.Parallel because hooks are global.
+
+	base := errors.Base("base")
+	withStack := errors.New("error")
+
+	tests := []struct {
+		name     string
+		create   func() errors.E
+		kind     errors.EventKind
+		newStack bool
+	}{
+		{"New", func() errors.E { return errors.New("error") }, errors.EventNew, true},
+		{"Errorf", func() errors.E { return errors.Errorf("error %d", 1) }, errors.EventErrorf, true},
+		{"ErrorfWrap", func() errors.E { return errors.Errorf("error: %w", withStack) }, errors.EventErrorf, false},
+		{"Wrap", func() errors.E { return errors.Wrap(withStack, "error") }, errors.EventWrap, true},
+		{"Wrapf", func() errors.E { return errors.Wrapf(withStack, "error %d", 1) }, errors.EventWrapf, true},
+		{"WrapWith", func() errors.E { return errors.WrapWith(withStack, base) }, errors.EventWrapWith, true},
+		{"Join", func() errors.E { return errors.Join(base, withStack) }, errors.EventJoin, true},
+		{"JoinOne", func() errors.E { return errors.Join(base) }, errors.EventJoin, true},
+		{"Prefix", func() errors.E { return errors.Prefix(withStack, base) }, errors.EventPrefix, false},
+		{"WithStack", func() errors.E { return errors.WithStack(base) }, errors.EventWithStack, true},
+		{"WithDetails", func() errors.E { return errors.WithDetails(withStack, "key", "value") }, errors.EventWithDetails, false},
+		{"WithMessage", func() errors.E { return errors.WithMessage(base, "prefix") }, errors.EventWithMessage, true},
+		{"WithMessagef", func() errors.E { return errors.WithMessagef(withStack, "prefix %d", 1) }, errors.EventWithMessagef, false},
+		{"WithPublicMessage", func() errors.E { return errors.WithPublicMessage(base, "public") }, errors.EventWithPublicMessage, true},
+		{"WithExitCode", func() errors.E { return errors.WithExitCode(withStack, 2) }, errors.EventWithExitCode, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			var errs []errors.E
+			var events []errors.Event
+			unregister := errors.OnCreate(func(err errors.E, event errors.Event) {
+				errs = append(errs, err)
+				events = append(events, event)
+			})
+			err := tt.create()
+			unregister()
+
+			require.Len(t, events, 1)
+			assert.Same(t, err, errs[0])
+			assert.Equal(t, tt.kind, events[0].Kind)
+			assert.Equal(t, tt.newStack, events[0].NewStack)
+			// The call site is the function literal calling the constructor.
+			frame := events[0].Frame()
+			assert.Regexp(t, `^gitlab\.com/tozd/go/errors_test\.TestOnCreate\.func\d+$`, frame.Function)
+			assert.True(t, strings.HasSuffix(frame.File, "/event_test.go"), frame.File)
+		})
+	}
+
+	t.Run("existing", func(t *testing.T) {
+		calls := 0
+		unregister := errors.OnCreate(func(errors.E, errors.Event) {
+			calls++
+		})
+		defer unregister()
+
+		// Existing error with a stack trace is returned as-is.
+		assert.Same(t, withStack, errors.WithStack(withStack))
+		assert.Same(t, withStack, errors.Join(withStack))
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("unregister", func(t *testing.T) {
+		var calls []string
+		unregisterFirst := errors.OnCreate(func(errors.E, errors.Event) {
+			calls = append(calls, "first")
+		})
+		unregisterSecond := errors.OnCreate(func(errors.E, errors.Event) {
+			calls = append(calls, "second")
+		})
+		_ = errors.New("error")
+		unregisterFirst()
+		_ = errors.New("error")
+		unregisterSecond()
+		_ = errors.New("error")
+		assert.Equal(t, []string{"first", "second", "second"}, calls)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"runtime"
+	"testing/fstest"
 
 	"gitlab.com/tozd/go/errors"
 )
@@ -598,3 +599,46 @@ func ExamplePrefix_printf() {
 	//
 	// 	EOF
 }
+
+func ExampleWithPublicMessage() {
+	base := errors.Base("not found")
+	errE := errors.WithDetails(errors.WithPublicMessage(base, "Image does not exist."), "filename", "star.png")
+	fmt.Println(errE)
+	fmt.Println(errors.PublicMessage(errE))
+	fmt.Printf("%v\n", errors.Formatter{Error: errE, Public: true})
+	// Output:
+	// not found
+	// Image does not exist.
+	// Image does not exist.
+}
+
+func ExamplePublicMessage() {
+	base := errors.Base("not found")
+	errE := errors.Wrapf(errors.WithPublicMessage(base, "Image does not exist."), "image %s not found", "star.png")
+	fmt.Println(errE)
+	fmt.Println(errors.PublicMessage(errE))
+	// Output:
+	// image star.png not found
+	// Image does not exist.
+}
+
+func ExampleCatalog_Localize() {
+	errNotFound := errors.Base("not found")
+
+	catalog, errE := errors.NewCatalog(fstest.MapFS{
+		"en.json": &fstest.MapFile{Data: []byte(`{"notFound": "Image {filename} does not exist."}`)},
+		"sl.json": &fstest.MapFile{Data: []byte(`{"notFound": "Slika {filename} ne obstaja."}`)},
+	})
+	if errE != nil {
+		panic(errE)
+	}
+	catalog.DefaultLanguage = "en"
+	catalog.Register(errNotFound, "notFound")
+
+	errE = errors.WithDetails(errNotFound, "filename", "star.png")
+	fmt.Println(catalog.Localize(errE, "sl-SI"))
+	fmt.Println(catalog.Localize(errE, "de"))
+	// Output:
+	// Slika star.png ne obstaja.
+	// Image star.png does not exist.
+}
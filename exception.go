@@ -0,0 +1,130 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// DefaultExceptionDetailsPrefix is the prefix used by ExceptionAttributes
+// for attributes with error details.
+const DefaultExceptionDetailsPrefix = "exception.details."
+
+// Exception converts errors into attributes following the OpenTelemetry
+// semantic conventions for exceptions.
+type Exception struct {
+	// Value of the "exception.escaped" attribute. Set it to true when the error
+	// is escaping the scope of the span (e.g., it is returned from the function
+	// the span is for).
+	Escaped bool `exhaustruct:"optional"`
+
+	// Prefix for attributes with error details. If empty, details are not included.
+	DetailsPrefix string `exhaustruct:"optional"`
+}
+
+// ExceptionAttributes returns attributes of err following the OpenTelemetry
+// semantic conventions for exceptions, using DefaultExceptionDetailsPrefix
+// for details and with "exception.escaped" set to false.
+//
+// See Exception.Attributes for more information.
+func ExceptionAttributes(err error) map[string]interface{} {
+	return Exception{
+		Escaped:       false,
+		DetailsPrefix: DefaultExceptionDetailsPrefix,
+	}.Attributes(err)
+}
+
+// Attributes returns attributes of err following the OpenTelemetry semantic
+// conventions for exceptions. They can be recorded on a span (e.g., as attributes
+// of an "exception" event) with any tracer, without depending on OpenTelemetry.
+//
+// The following attributes are returned:
+//
+//	exception.type        the concrete Go type of the closest error in err's tree which
+//	                      does not come from this package (e.g., *fs.PathError),
+//	                      or of the innermost unwrapped error if there is no such error
+//	exception.message     the error message
+//	exception.stacktrace  the stack trace of err formatted with StackFormatter using %+v,
+//	                      only if err has a stack trace
+//	exception.escaped     the value of Escaped
+//
+// Details of err (see AllDetails) are added under DetailsPrefix. Nested maps are
+// flattened into keys joined with a dot. Values which are not strings, booleans,
+// or numbers are marshaled as JSON.
+//
+// If err is nil, Attributes returns nil.
+func (e Exception) Attributes(err error) map[string]interface{} {
+	if err == nil {
+		return nil
+	}
+
+	attributes := map[string]interface{}{}
+
+	if e.DetailsPrefix != "" {
+		flattenDetails(attributes, e.DetailsPrefix, AllDetails(err))
+	}
+
+	typeErr := closest(err, func(er error) bool {
+		return !isOwnError(er)
+	})
+	if typeErr == nil {
+		// All errors come from this package, so we use the innermost one
+		// (e.g., the one made by New) instead of the outermost wrapper.
+		typeErr = err
+		var cycle unwrapCycle
+		for !cycle.repeated(typeErr) {
+			u, ok := typeErr.(unwrapper) //nolint:errorlint
+			if !ok || u.Unwrap() == nil {
+				break
+			}
+			typeErr = u.Unwrap()
+		}
+	}
+	attributes["exception.type"] = reflect.TypeOf(typeErr).String()
+	attributes["exception.message"] = err.Error()
+	attributes["exception.escaped"] = e.Escaped
+
+	st := getExistingStackTrace(err)
+	if len(st) > 0 {
+		attributes["exception.stacktrace"] = fmt.Sprintf("%+v", StackFormatter{st})
+	} else {
+		placeholderErr, ok := err.(placeholderStackTracer)
+		if ok {
+			placeholderSt := placeholderErr.StackTrace()
+			if len(placeholderSt) > 0 {
+				attributes["exception.stacktrace"] = fmt.Sprintf("%+v", placeholderSt)
+			}
+		}
+	}
+
+	return attributes
+}
+
+// flattenDetails adds details to attributes under prefix,
+// flattening nested maps.
+func flattenDetails(attributes map[string]interface{}, prefix string, details map[string]interface{}) {
+	keys := make([]string, 0, len(details))
+	for key := range details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch value := details[key].(type) {
+		case map[string]interface{}:
+			flattenDetails(attributes, prefix+key+".", value)
+		case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			attributes[prefix+key] = value
+		case json.Number:
+			attributes[prefix+key] = string(value)
+		default:
+			data, err := marshalWithoutEscapeHTML(value)
+			if err != nil {
+				attributes[prefix+key] = fmt.Sprintf("%v", value)
+			} else {
+				attributes[prefix+key] = string(data)
+			}
+		}
+	}
+}
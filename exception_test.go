@@ -0,0 +1,77 @@
+package errors_test
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func TestExceptionAttributes(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, errors.ExceptionAttributes(nil))
+
+	pathErr := &fs.PathError{Op: "open", Path: "/file", Err: fs.ErrNotExist}
+	err := errors.WithDetails(
+		errors.Wrap(pathErr, "cannot open file"),
+		"path", "/file",
+		"attempt", 2,
+		"nested", map[string]interface{}{"key": "value", "deeper": map[string]interface{}{"ok": true}},
+		"list", []string{"a", "b"},
+	)
+
+	attributes := errors.ExceptionAttributes(err)
+	stacktrace, ok := attributes["exception.stacktrace"].(string)
+	require.True(t, ok)
+	assert.Regexp(t, `^gitlab\.com/tozd/go/errors_test\.TestExceptionAttributes\n\t.+/exception_test\.go:\d+\n`, stacktrace)
+	delete(attributes, "exception.stacktrace")
+	assert.Equal(t, map[string]interface{}{
+		"exception.type":                     "*fs.PathError",
+		"exception.message":                  "cannot open file",
+		"exception.escaped":                  false,
+		"exception.details.path":             "/file",
+		"exception.details.attempt":          2,
+		"exception.details.nested.key":       "value",
+		"exception.details.nested.deeper.ok": true,
+		"exception.details.list":             `["a","b"]`,
+	}, attributes)
+
+	attributes = errors.Exception{Escaped: true, DetailsPrefix: "error."}.Attributes(errors.WithDetails(errors.New("error"), "key", "value"))
+	delete(attributes, "exception.stacktrace")
+	assert.Equal(t, map[string]interface{}{
+		"exception.type":    "*errors.fundamentalError",
+		"exception.message": "error",
+		"exception.escaped": true,
+		"error.key":         "value",
+	}, attributes)
+
+	attributes = errors.Exception{}.Attributes(errors.WithDetails(errors.Base("base"), "key", "value"))
+	assert.Contains(t, attributes, "exception.stacktrace")
+	delete(attributes, "exception.stacktrace")
+	assert.Equal(t, map[string]interface{}{
+		"exception.type":    "*errors.errorString",
+		"exception.message": "base",
+		"exception.escaped": false,
+	}, attributes)
+
+	attributes = errors.ExceptionAttributes(errors.Base("base"))
+	assert.Equal(t, map[string]interface{}{
+		"exception.type":    "*errors.errorString",
+		"exception.message": "base",
+		"exception.escaped": false,
+	}, attributes)
+
+	placeholder, errE := errors.UnmarshalJSON([]byte(`{"error":"error","key":{"a":1},"stack":[{"name":"main.main","file":"/main.go","line":10}]}`))
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, map[string]interface{}{
+		"exception.type":          "*errors.placeholderError",
+		"exception.message":       "error",
+		"exception.escaped":       false,
+		"exception.stacktrace":    "main.main\n\t/main.go:10\n",
+		"exception.details.key.a": float64(1),
+	}, errors.ExceptionAttributes(placeholder))
+}
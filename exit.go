@@ -0,0 +1,143 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// MainFormatEnv is the name of the environment variable which controls
+// how Main formats the error.
+const MainFormatEnv = "ERRORS_FORMAT"
+
+type exitCoder interface {
+	ExitCode() int
+}
+
+// WithExitCode annotates err with a process exit code.
+// If err does not have a stack trace, stack strace is recorded as well.
+// If err is nil, WithExitCode returns nil.
+//
+// Use WithExitCode to distinguish between different kinds of errors
+// (e.g., usage errors, not found errors, internal failures) when
+// the program exits. Use ExitCode to obtain the exit code.
+func WithExitCode(err error, code int) E {
+	if err == nil {
+		return nil
+	}
+
+	st := getExistingStackTrace(err)
+	newStack := len(st) == 0
+	if newStack {
+		st = callers(0)
+	}
+
+	return record(&exitCodeError{
+		annotation: newAnnotation(err, st),
+		code:       code,
+	}, EventWithExitCode, newStack, 0)
+}
+
+// exitCodeError wraps another error and has its own
+// stack and exit code, but does not have its own msg.
+type exitCodeError struct {
+	annotation
+	code int
+}
+
+func (e *exitCodeError) Error() string {
+	if isCalledFromRuntimePanic() {
+		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
+	}
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Format(s fmt.State, verb rune) {
+	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
+}
+
+func (e *exitCodeError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
+}
+
+func (e *exitCodeError) ExitCode() int {
+	return e.code
+}
+
+// ExitCode returns the process exit code for err.
+//
+// It returns the result of calling the ExitCode method on the closest
+// error in err's tree which has that method returning a non-negative
+// exit code. The tree is traversed breadth-first, so exit codes closer
+// to err take precedence. This also makes ExitCode honor exit codes
+// of *exec.ExitError errors.
+//
+// If there is no such error, ExitCode returns 1.
+// If err is nil, ExitCode returns 0.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	e := closest(err, func(e error) bool {
+		c, ok := e.(exitCoder)
+		return ok && c.ExitCode() >= 0
+	})
+	if e != nil {
+		return e.(exitCoder).ExitCode() //nolint:forcetypeassert,errcheck,errorlint
+	}
+
+	return 1
+}
+
+// Main calls run and if it returns an error, it formats the error
+// to the standard error and exits the process with the exit code
+// obtained by calling ExitCode on the error.
+// If run returns nil, Main returns.
+//
+// How the error is formatted is controlled by the environment
+// variable named by MainFormatEnv:
+//
+//	message    only the error message
+//	details    the error message and details, recursively
+//	full       the error message, details and stack trace, recursively,
+//	           this is the default
+//	json       the error marshaled as JSON
+//
+// Use Main in your program's main function:
+//
+//	func main() {
+//	        errors.Main(run)
+//	}
+func Main(run func() error) {
+	code := runMain(run, os.Stderr, os.Getenv(MainFormatEnv))
+	if code != 0 {
+		os.Exit(code)
+	}
+}
+
+func runMain(run func() error, w io.Writer, format string) int {
+	err := run()
+	if err == nil {
+		return 0
+	}
+
+	switch format {
+	case "message":
+		_, _ = fmt.Fprintf(w, "%s\n", Formatter{Error: err})
+	case "details":
+		_, _ = fmt.Fprintf(w, "% #-.1v", Formatter{Error: err})
+	case "json":
+		data, errE := marshalJSONAnyError(err)
+		if errE != nil {
+			// We fallback to full formatting.
+			_, _ = fmt.Fprintf(w, "% #-+.1v", Formatter{Error: err})
+		} else {
+			_, _ = fmt.Fprintf(w, "%s\n", data)
+		}
+	default:
+		_, _ = fmt.Fprintf(w, "% #-+.1v", Formatter{Error: err})
+	}
+
+	return ExitCode(err)
+}
@@ -0,0 +1,120 @@
+package errors_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	cmdErr := exec.Command("sh", "-c", "exit 3").Run()
+	require.Error(t, cmdErr)
+
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("error"), 1},
+		{errors.Base("error"), 1},
+		{errors.WithExitCode(errors.New("error"), 2), 2},
+		{errors.WithExitCode(errors.New("error"), 0), 0},
+		{errors.WithExitCode(errors.New("error"), -1), 1},
+		{errors.WithDetails(errors.WithExitCode(errors.New("error"), 2), "key", "value"), 2},
+		{errors.WithExitCode(errors.WithExitCode(errors.New("error"), 2), 3), 3},
+		{errors.WithPublicMessage(errors.WithExitCode(errors.New("error"), 2), "public"), 2},
+		{errors.Wrap(errors.WithExitCode(errors.New("error"), 2), "wrap"), 2},
+		{errors.Join(errors.New("error"), errors.WithExitCode(errors.New("error"), 2)), 2},
+		{cmdErr, 3},
+		{errors.Wrap(cmdErr, "command failed"), 3},
+		{errors.WithExitCode(cmdErr, 4), 4},
+	}
+
+	for k, tt := range tests {
+		tt := tt
+
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, errors.ExitCode(tt.err))
+		})
+	}
+}
+
+func TestWithExitCode(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, errors.WithExitCode(nil, 2))
+
+	base := errors.Base("error")
+	err := errors.WithExitCode(base, 2)
+	assert.EqualError(t, err, "error")
+	assert.ErrorIs(t, err, base)
+	assert.NotEmpty(t, err.StackTrace())
+	assert.Equal(t, "error\n", fmt.Sprintf("%#v", err))
+
+	// Only errors made by WithExitCode have the ExitCode method.
+	_, ok := errors.WithPublicMessage(base, "public").(interface{ ExitCode() int }) //nolint:errorlint
+	assert.False(t, ok)
+}
+
+func TestMainExit(t *testing.T) {
+	t.Parallel()
+
+	binary := filepath.Join(t.TempDir(), "main")
+	output, err := exec.Command("go", "build", "-o", binary, "testdata/main.go").CombinedOutput() //nolint:noctx
+	require.NoError(t, err, string(output))
+
+	tests := []struct {
+		arg    string
+		format string
+		code   int
+		want   string
+	}{
+		{"success", "", 0, `^$`},
+		{"usage", "message", 2, `^usage error\n$`},
+		{"usage", "details", 2, `^usage error\narg=foo\n$`},
+		{"usage", "", 2, `^usage error\n` +
+			`arg=foo\n` +
+			`stack trace \(most recent call first\):\n` +
+			`main\.run\n` +
+			`\t.*/testdata/main.go:16\n` +
+			`(.+\n\t.+:\d+\n)+$`},
+		{"usage", "json", 2, `^\{"arg":"foo","error":"usage error","stack":\[\{"name":"main\.run","file":".*/testdata/main\.go","line":16\},.*\]\}\n$`},
+		{"internal", "message", 1, `^internal error\n$`},
+	}
+
+	for k, tt := range tests {
+		tt := tt
+
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			stderr := &bytes.Buffer{}
+			cmd := exec.Command(binary, tt.arg) //nolint:gosec,noctx
+			cmd.Env = append(os.Environ(), errors.MainFormatEnv+"="+tt.format)
+			cmd.Stderr = stderr
+
+			err := cmd.Run()
+			if tt.code == 0 {
+				assert.NoError(t, err) //nolint:testifylint
+			} else {
+				var exitError *exec.ExitError
+				require.ErrorAs(t, err, &exitError)
+				assert.Equal(t, tt.code, exitError.ExitCode())
+				assert.Equal(t, tt.code, errors.ExitCode(err))
+			}
+			assert.Regexp(t, tt.want, stderr.String())
+		})
+	}
+}
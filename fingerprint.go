@@ -0,0 +1,177 @@
+package errors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+type fingerprinter interface {
+	Fingerprint() string
+}
+
+// fingerprintSize is the number of bytes of the hash used for the fingerprint.
+const fingerprintSize = 16
+
+// Fingerprint returns a string which identifies the kind of err, so that
+// multiple occurrences of the same error can be grouped together (e.g., when
+// collecting errors in production).
+//
+// The fingerprint is computed from the whole tree of err: from messages of
+// base errors (errors without a stack trace made with Base, Basef, BaseWrap,
+// BaseWrapf, or the standard library), types of errors not from this package,
+// and names of functions of the top in-app stack frames (frames not from
+// the Go standard library). Line numbers, details, and messages of other
+// errors (which often contain variable parts) are ignored, so the fingerprint
+// is stable across restarts and deploys which do not change those functions.
+//
+// If err implements the Fingerprint method returning a non-empty string,
+// its result is returned instead. This is how placeholder errors from
+// UnmarshalJSON return the fingerprint stored in JSON (see Formatter).
+// Otherwise the fingerprint of a placeholder error is computed from its data,
+// but because placeholder errors do not retain types of original errors,
+// it generally differs from the fingerprint of the original error.
+//
+// If err is nil, Fingerprint returns an empty string.
+func Fingerprint(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	f, ok := err.(fingerprinter) //nolint:errorlint
+	if ok {
+		fingerprint := f.Fingerprint()
+		if fingerprint != "" {
+			return fingerprint
+		}
+	}
+
+	h := sha256.New()
+	writeFingerprint(h, err, "", nil)
+	return hex.EncodeToString(h.Sum(nil)[:fingerprintSize])
+}
+
+// writeFingerprint writes parts of err's tree relevant for the fingerprint to w.
+// parentFunction is the top in-app function of the closest ancestor error with
+// a stack trace, so that the same stack trace shared between wrapping errors is
+// written only once. ancestors are errors on the path from the root to err,
+// used to detect cycles.
+func writeFingerprint(w io.Writer, err error, parentFunction string, ancestors []error) {
+	if isAncestor(err, ancestors) {
+		_, _ = io.WriteString(w, "cycle\n")
+		return
+	}
+	ancestors = append(ancestors[:len(ancestors):len(ancestors)], err)
+
+	if !isOwnError(err) {
+		_, _ = io.WriteString(w, "type:")
+		_, _ = io.WriteString(w, reflect.TypeOf(err).String())
+		_, _ = io.WriteString(w, "\n")
+	}
+
+	function, hasStack := topInAppFunction(err)
+	if !hasStack {
+		if isFingerprintBaseError(err) {
+			_, _ = io.WriteString(w, "base:")
+			_, _ = io.WriteString(w, err.Error())
+			_, _ = io.WriteString(w, "\n")
+		}
+	} else if function != parentFunction {
+		_, _ = io.WriteString(w, "frame:")
+		_, _ = io.WriteString(w, function)
+		_, _ = io.WriteString(w, "\n")
+	}
+	if hasStack {
+		parentFunction = function
+	}
+
+	switch u := err.(type) { //nolint:errorlint
+	case unwrapperJoined:
+		_, _ = io.WriteString(w, "joined:\n")
+		for _, er := range u.Unwrap() {
+			if er != nil {
+				writeFingerprint(w, er, parentFunction, ancestors)
+			}
+		}
+		_, _ = io.WriteString(w, "end\n")
+	case unwrapper:
+		er := u.Unwrap()
+		if er != nil {
+			writeFingerprint(w, er, parentFunction, ancestors)
+		}
+	}
+}
+
+// isOwnError returns true if err is made by this package.
+func isOwnError(err error) bool {
+	_, ok := err.(ownError) //nolint:errorlint
+	return ok
+}
+
+// isFingerprintBaseError returns true if err is a base error with
+// a message which is expected to be constant.
+func isFingerprintBaseError(err error) bool {
+	switch err.(type) { //nolint:errorlint
+	case *base, *placeholderError, *placeholderCauseError, *placeholderJoinedError, *placeholderJoinedCauseError, *placeholderRefError:
+		return true
+	}
+	// Messages of errors made by fmt.Errorf contain formatted arguments,
+	// so we do not use them and recurse into wrapped errors instead.
+	return reflect.TypeOf(err).String() == "*errors.errorString"
+}
+
+// topInAppFunction returns the name of the function of the top in-app stack frame
+// of err's own stack trace. It returns false if err does not have a stack trace.
+func topInAppFunction(err error) (string, bool) {
+	st := getExistingStackTrace(err)
+	if len(st) > 0 {
+		frames := runtime.CallersFrames(st)
+		for {
+			f, more := frames.Next()
+			if isInAppFunction(f.Function) {
+				return f.Function, true
+			}
+			if !more {
+				return "", true
+			}
+		}
+	}
+
+	placeholderErr, ok := err.(placeholderStackTracer)
+	if !ok {
+		return "", false
+	}
+	placeholderSt := placeholderErr.StackTrace()
+	if len(placeholderSt) == 0 {
+		return "", false
+	}
+	for _, f := range placeholderSt {
+		if isInAppFunction(f.Name) {
+			return f.Name, true
+		}
+	}
+	return "", true
+}
+
+// isInAppFunction returns true if function is not from the Go standard library.
+// Packages from the standard library do not have a dot in the first element
+// of their import path.
+func isInAppFunction(function string) bool {
+	if function == "" {
+		return false
+	}
+	pkg := function
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		if strings.Contains(pkg[:strings.Index(pkg, "/")], ".") {
+			return true
+		}
+		pkg = pkg[i+1:]
+	}
+	if i := strings.Index(pkg, "."); i >= 0 {
+		pkg = pkg[:i]
+	}
+	return pkg == "main" || strings.HasSuffix(pkg, "_test")
+}
@@ -0,0 +1,123 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+var (
+	errFingerprintNotFound = errors.Base("not found")
+	errFingerprintDenied   = errors.Base("denied")
+)
+
+func fingerprintFind(id int, base error) errors.E {
+	return errors.WithDetails(errors.WithMessagef(base, "user %d", id), "id", id)
+}
+
+func fingerprintFindOther(id int, base error) errors.E {
+	return errors.WithDetails(errors.WithMessagef(base, "user %d", id), "id", id)
+}
+
+func fingerprintNew(id int) errors.E {
+	return errors.Errorf("user %d not found", id)
+}
+
+func fingerprintWrap(err error) errors.E {
+	return errors.Wrap(err, "wrapped")
+}
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "", errors.Fingerprint(nil))
+
+	fingerprint := errors.Fingerprint(fingerprintFind(1, errFingerprintNotFound))
+	assert.Regexp(t, `^[0-9a-f]{32}$`, fingerprint)
+
+	tests := []struct {
+		name  string
+		err   error
+		equal bool
+	}{
+		{"different message and details", fingerprintFind(2, errFingerprintNotFound), true},
+		{"additional details", errors.WithDetails(fingerprintFind(2, errFingerprintNotFound), "key", "value"), true},
+		{"different base error", fingerprintFind(1, errFingerprintDenied), false},
+		{"different function", fingerprintFindOther(1, errFingerprintNotFound), false},
+		{"wrapped", fingerprintWrap(fingerprintFind(1, errFingerprintNotFound)), false},
+		{"joined", errors.Join(fingerprintFind(1, errFingerprintNotFound), errors.Base("other")), false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if tt.equal {
+				assert.Equal(t, fingerprint, errors.Fingerprint(tt.err))
+			} else {
+				assert.NotEqual(t, fingerprint, errors.Fingerprint(tt.err))
+			}
+		})
+	}
+
+	// Messages of errors with stack traces are ignored.
+	assert.Equal(t, errors.Fingerprint(fingerprintNew(1)), errors.Fingerprint(fingerprintNew(2)))
+
+	// Messages of foreign errors are ignored, but their types are not.
+	pathErr1 := &fs.PathError{Op: "open", Path: "/one", Err: fs.ErrNotExist}
+	pathErr2 := &fs.PathError{Op: "open", Path: "/two", Err: fs.ErrNotExist}
+	assert.Equal(t, errors.Fingerprint(fingerprintWrap(pathErr1)), errors.Fingerprint(fingerprintWrap(pathErr2)))
+	assert.NotEqual(t, errors.Fingerprint(fingerprintWrap(pathErr1)), errors.Fingerprint(fingerprintWrap(fs.ErrNotExist)))
+
+	// Messages of errors made by fmt.Errorf are ignored, but wrapped errors are not.
+	assert.Equal(t, errors.Fingerprint(fmt.Errorf("read %s: %w", "one", io.EOF)), errors.Fingerprint(fmt.Errorf("read %s: %w", "two", io.EOF)))
+	assert.NotEqual(t, errors.Fingerprint(fmt.Errorf("read %s: %w", "one", io.EOF)), errors.Fingerprint(fmt.Errorf("read %s: %w", "one", io.ErrUnexpectedEOF)))
+	assert.Equal(t, errors.Fingerprint(fmt.Errorf("%w, %w", io.EOF, fs.ErrNotExist)), errors.Fingerprint(fmt.Errorf("%w and %w", io.EOF, fs.ErrNotExist)))
+}
+
+func TestFingerprintJSON(t *testing.T) {
+	t.Parallel()
+
+	err := fingerprintWrap(fingerprintFind(1, errFingerprintNotFound))
+	fingerprint := errors.Fingerprint(err)
+
+	data, e := json.Marshal(err)
+	require.NoError(t, e)
+	assert.NotContains(t, string(data), `"fingerprint":`)
+
+	data, e = json.Marshal(errors.Formatter{Error: err, Fingerprint: true})
+	require.NoError(t, e)
+	assert.Contains(t, string(data), fmt.Sprintf(`"fingerprint":"%s"`, fingerprint))
+
+	placeholder, errE := errors.UnmarshalJSON(data)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, fingerprint, errors.Fingerprint(placeholder))
+	assert.NotContains(t, errors.AllDetails(placeholder), "fingerprint")
+
+	data2, e := json.Marshal(placeholder)
+	require.NoError(t, e)
+	assert.Equal(t, string(data), string(data2))
+
+	// Without a stored fingerprint, it is computed from placeholder's data.
+	data, e = json.Marshal(err)
+	require.NoError(t, e)
+	placeholder, errE = errors.UnmarshalJSON(data)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Regexp(t, `^[0-9a-f]{32}$`, errors.Fingerprint(placeholder))
+	placeholder2, errE := errors.UnmarshalJSON(data)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, errors.Fingerprint(placeholder), errors.Fingerprint(placeholder2))
+
+	data, e = json.Marshal(errors.Formatter{Error: nil, Fingerprint: true})
+	require.NoError(t, e)
+	assert.Equal(t, "null", string(data))
+}
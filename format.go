@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -22,6 +23,16 @@ const (
 	stackTraceHelp     = "stack trace (most recent call first):\n"
 	multipleErrorsHelp = "the above error joins errors:\n"
 	causeHelp          = "the above error was caused by the following error:\n"
+	returnTraceHelp    = "return trace (first return first):\n"
+	boundaryHelp       = "rethrown at:\n"
+)
+
+// Connectors used to draw the tree of errors.
+const (
+	treeBranch   = "├─ "
+	treeLast     = "└─ "
+	treeVertical = "│  "
+	treeSpace    = "   "
 )
 
 // Similar to one in fmt/print.go.
@@ -67,7 +78,8 @@ func writeLinesPrefixed(w io.Writer, linePrefix, s string) {
 }
 
 func useKnownInterface(err error) bool {
-	for err != nil {
+	var cycle unwrapCycle
+	for err != nil && !cycle.repeated(err) {
 		switch err.(type) { //nolint:errorlint
 		case stackTracer, pkgStackTracer, goErrorsStackTracer, erisStackTracer, detailer, placeholderStackTracer:
 			// We return true only on interfaces with data. Not on causer.
@@ -101,9 +113,7 @@ func useFormatter(err error) bool {
 func isForeignFormatter(err error) bool {
 	// Our errors implement fmt.Formatter but we want to return false for them because
 	// they just call into our Formatter which would lead to infinite recursion.
-	switch err.(type) { //nolint:errorlint
-	case *fundamentalError, *msgError, *msgJoinedError, *noMsgError, *causeError, *wrapError,
-		*placeholderError, *placeholderCauseError, *placeholderJoinedError, *placeholderJoinedCauseError:
+	if isOwnError(err) {
 		return false
 	}
 
@@ -112,57 +122,96 @@ func isForeignFormatter(err error) bool {
 	return ok
 }
 
-func (f Formatter) formatError(s fmt.State, w io.Writer, indent int, err error) {
-	linePrefix := ""
-	if indent > 0 {
-		width, ok := s.Width()
-		if ok {
-			linePrefix = strings.Repeat(strings.Repeat(" ", width), indent)
-		} else {
-			linePrefix = strings.Repeat("\t", indent)
-		}
+// linePrefix returns the prefix for lines indented indent times.
+func (o FormatOptions) linePrefix(indent int) string {
+	if indent <= 0 {
+		return ""
 	}
+	if o.Indent > 0 {
+		return strings.Repeat(strings.Repeat(" ", o.Indent), indent)
+	}
+	return strings.Repeat("\t", indent)
+}
+
+// formatError formats err and recurses into its joined errors and cause.
+// Errors on the path from the root are tracked in v to detect cycles
+// and path is JSON pointer to err (as it would be when marshaled).
+func (o FormatOptions) formatError(w io.Writer, indent, depth int, err error, v *visitedErrors, path string) {
+	linePrefix := o.linePrefix(indent)
 
 	var cause error
 	var errs []error
-	precision, ok := s.Precision()
-	if !ok {
-		// We explicitly set it to 0.
-		// See: https://github.com/golang/go/issues/61913
-		precision = 0
+
+	if o.Mode == FormatTree && err != nil {
+		o.formatTree(w, "", "", "", depth, err, v, path)
+		return
 	}
 
-	if precision >= 2 && isForeignFormatter(err) || err == nil {
-		writeLinesPrefixed(w, linePrefix, fmt.Sprintf(formatString(s, 'v'), err))
+	if o.Mode == FormatLogfmt && err != nil {
+		o.formatLogfmt(w, err, v, path)
+		return
+	}
+
+	if err != nil {
+		// An error already on the path from the root would make a cycle.
+		ref, ok := v.backReference(err)
+		if ok {
+			writeLinesPrefixed(w, linePrefix, ref+"\n")
+			return
+		}
+		v.push(err, path)
+		defer v.pop()
+	}
+
+	// Methods of foreign errors might panic. We format the panic in place of the rest of the error.
+	method := "Unwrap"
+	defer recoverPanic(&method, func(msg string) {
+		writeLinesPrefixed(w, linePrefix, msg+"\n")
+	})
+
+	if o.preferFormatter() && isForeignFormatter(err) || err == nil {
+		writeLinesPrefixed(w, linePrefix, fmt.Sprintf(o.formatString(), err))
 		// Here we return because we assume formatting does recurse itself or at least
 		// the user requested us to not recuse if the error implements fmt.Formatter.
 		return
 	}
 
 	if useFormatter(err) {
-		writeLinesPrefixed(w, linePrefix, fmt.Sprintf(formatString(s, 'v'), err))
+		writeLinesPrefixed(w, linePrefix, fmt.Sprintf(o.formatString(), err))
 		// Here we still recurse ourselves because we assume formatting just formats the error and
 		// does not recurse if it does not implement those interfaces which we checked in useFormatter.
-		if precision == 1 || precision == 3 {
+		if o.recursive() {
 			cause, errs = causeOrJoined(err)
 		}
 	} else {
-		f.formatMsg(w, linePrefix, err)
+		method = "Error"
+		o.formatMsg(w, linePrefix, err)
+		method = "Details"
 		var details map[string]interface{}
-		if s.Flag('#') {
+		if o.Details {
 			details, cause, errs = allDetailsUntilCauseOrJoined(err)
-		} else if precision == 1 || precision == 3 {
+		} else if o.recursive() {
 			cause, errs = causeOrJoined(err)
 		}
-		if s.Flag('#') {
-			f.formatDetails(w, linePrefix, details)
+		if o.Details {
+			o.formatDetails(w, linePrefix, err, details, v)
 		}
-		if s.Flag('+') {
-			f.formatStack(s, w, linePrefix, err)
+		method = "StackTrace"
+		if o.Stack {
+			o.formatStack(w, linePrefix, err)
+			o.formatBoundaries(w, linePrefix, err)
+		}
+		if o.ReturnTrace {
+			o.formatReturnTrace(w, linePrefix, err)
 		}
 	}
 
-	if precision == 1 || precision == 3 { //nolint:nestif
+	if o.recursive() { //nolint:nestif
+		joined, omitted, depthLimit := o.joinedOrDepthLimit(depth, err, cause, errs)
+		if depthLimit {
+			cause = nil
+		}
+
 		buf := new(bytes.Buffer)
 
 		// It is possible that both cause and errs is set. In that case we first
@@ -171,50 +220,58 @@ func (f Formatter) formatError(s fmt.State, w io.Writer, indent int, err error)
 		// indented it is hopefully clearer that its "above error" does not mean
 		// the last error among joined but the one higher up before indentation.
 
-		if len(errs) > 0 {
-			first := true
-			for _, er := range errs {
-				// er should never be nil, but we still check.
-				// We also make sure we do not repeat cause here or repeat an error without any additional information.
-				if er != nil && er != cause && !isSubsumedError(err, er) { //nolint:errorlint,err113
-					// We format error to the buffer so that we can see if anything was written.
-					buf.Reset()
-					f.formatError(s, buf, indent+1, er)
-					// If nothing was written, we skip this error.
-					if buf.Len() == 0 {
-						continue
-					}
+		written := 0
+		for _, er := range joined {
+			// We format error to the buffer so that we can see if anything was written.
+			buf.Reset()
+			o.formatError(buf, indent+1, depth+1, er, v, path+"/errors/"+strconv.Itoa(written))
+			// If nothing was written, we skip this error.
+			if buf.Len() == 0 {
+				continue
+			}
+			written++
 
-					if first {
-						first = false
-						if s.Flag('-') {
-							if s.Flag(' ') {
-								_, _ = io.WriteString(w, "\n")
-							}
-							writeLinesPrefixed(w, linePrefix, multipleErrorsHelp)
-						}
-					}
-					if s.Flag(' ') {
+			if written == 1 {
+				if o.Help {
+					if o.Spacing {
 						_, _ = io.WriteString(w, "\n")
 					}
-					_, _ = io.Copy(w, buf)
+					writeLinesPrefixed(w, linePrefix, multipleErrorsHelp)
 				}
 			}
+			if o.Spacing {
+				_, _ = io.WriteString(w, "\n")
+			}
+			_, _ = io.Copy(w, buf)
+		}
+
+		if omitted > 0 {
+			if o.Spacing {
+				_, _ = io.WriteString(w, "\n")
+			}
+			writeLinesPrefixed(w, o.linePrefix(indent+1), moreErrors(omitted))
+		}
+
+		if depthLimit {
+			if o.Spacing {
+				_, _ = io.WriteString(w, "\n")
+			}
+			writeLinesPrefixed(w, linePrefix, depthLimitReached)
 		}
 
 		if cause != nil {
 			// We format error to the buffer so that we can see if anything was written.
 			buf.Reset()
-			f.formatError(s, buf, indent, cause)
+			o.formatError(buf, indent, depth+1, cause, v, path+"/cause")
 			// Only if something was written we continue.
 			if buf.Len() > 0 {
-				if s.Flag('-') {
-					if s.Flag(' ') {
+				if o.Help {
+					if o.Spacing {
 						_, _ = io.WriteString(w, "\n")
 					}
 					writeLinesPrefixed(w, linePrefix, causeHelp)
 				}
-				if s.Flag(' ') {
+				if o.Spacing {
 					_, _ = io.WriteString(w, "\n")
 				}
 				_, _ = io.Copy(w, buf)
@@ -223,17 +280,179 @@ func (f Formatter) formatError(s fmt.State, w io.Writer, indent int, err error)
 	}
 }
 
-func (f Formatter) formatMsg(w io.Writer, linePrefix string, err error) {
-	getMessage := f.GetMessage
+// maxDepth returns the maximum depth to recurse into, using
+// package limits (see SetLimits) when not set in options.
+func (o FormatOptions) maxDepth() int {
+	if o.MaxDepth > 0 {
+		return o.MaxDepth
+	}
+	return GetLimits().MaxDepth
+}
+
+// maxJoined returns the maximum number of joined errors to format, using
+// package limits (see SetLimits) when not set in options.
+func (o FormatOptions) maxJoined() int {
+	if o.MaxJoined > 0 {
+		return o.MaxJoined
+	}
+	return GetLimits().MaxJoined
+}
+
+// joinedOrDepthLimit returns joined errors of err to recurse into (excluding
+// the cause and errors without any additional information), the number of
+// omitted joined errors, and if the depth limit has been reached (in which
+// case err's cause should not be recursed into either). It takes into
+// account parts omitted from placeholder errors.
+func (o FormatOptions) joinedOrDepthLimit(depth int, err, cause error, errs []error) ([]error, int, bool) {
+	omitted := getOmittedParts(err)
+	if omitted.depthLimit {
+		return nil, 0, true
+	}
+
+	joined := []error{}
+	for _, er := range errs {
+		// er should never be nil, but we still check.
+		// We also make sure we do not repeat cause here or repeat an error without any additional information.
+		if er != nil && er != cause && !isSubsumedError(err, er) { //nolint:errorlint,err113
+			joined = append(joined, er)
+		}
+	}
+
+	maxDepth := o.maxDepth()
+	if maxDepth > 0 && depth >= maxDepth && (cause != nil || len(joined) > 0 || omitted.errors > 0) {
+		return nil, 0, true
+	}
+
+	maxJoined := o.maxJoined()
+	if maxJoined > 0 && len(joined) > maxJoined {
+		omitted.errors += len(joined) - maxJoined
+		joined = joined[:maxJoined]
+	}
+
+	return joined, omitted.errors, false
+}
+
+// formatTree formats err and recurses into its joined errors and cause, drawing
+// the tree with connectors. The first line of err is prefixed with firstPrefix and
+// label, other lines of err are prefixed with restPrefix.
+func (o FormatOptions) formatTree(w io.Writer, firstPrefix, restPrefix, label string, depth int, err error, v *visitedErrors, path string) {
+	// An error already on the path from the root would make a cycle.
+	ref, ok := v.backReference(err)
+	if ok {
+		_, _ = io.WriteString(w, firstPrefix+label+ref+"\n")
+		return
+	}
+	v.push(err, path)
+	defer v.pop()
+
+	buf := new(bytes.Buffer)
+
+	// Methods of foreign errors might panic. We format the panic in place of the rest of the error.
+	method := "Unwrap"
+	defer recoverPanic(&method, func(msg string) {
+		writeLinesPrefixed(buf, "", msg+"\n")
+		writeTreeLines(w, firstPrefix, restPrefix, label, buf.String())
+	})
+
+	var cause error
+	var errs []error
+	if useFormatter(err) {
+		writeLinesPrefixed(buf, "", fmt.Sprintf(o.formatString(), err))
+		cause, errs = causeOrJoined(err)
+	} else {
+		method = "Error"
+		o.formatMsg(buf, "", err)
+		method = "Details"
+		var details map[string]interface{}
+		details, cause, errs = allDetailsUntilCauseOrJoined(err)
+		if o.Details {
+			o.formatDetails(buf, "", err, details, v)
+		}
+		method = "StackTrace"
+		if o.Stack {
+			o.formatStack(buf, "", err)
+			o.formatBoundaries(buf, "", err)
+		}
+		if o.ReturnTrace {
+			o.formatReturnTrace(buf, "", err)
+		}
+	}
+
+	writeTreeLines(w, firstPrefix, restPrefix, label, buf.String())
+
+	joined, omitted, depthLimit := o.joinedOrDepthLimit(depth, err, cause, errs)
+	if depthLimit {
+		cause = nil
+	}
+
+	type treeChild struct {
+		label string
+		err   error
+		path  string
+	}
+	children := []treeChild{}
+	for i, er := range joined {
+		erPath := path + "/errors/" + strconv.Itoa(i)
+		if cause != nil {
+			children = append(children, treeChild{string(RelationWith) + ": ", er, erPath})
+		} else {
+			children = append(children, treeChild{string(RelationJoined) + ": ", er, erPath})
+		}
+	}
+	// Summaries are represented as children without an error.
+	if omitted > 0 {
+		children = append(children, treeChild{moreErrors(omitted), nil, ""})
+	}
+	if cause != nil {
+		children = append(children, treeChild{string(RelationCause) + ": ", cause, path + "/cause"})
+	}
+	if depthLimit {
+		children = append(children, treeChild{depthLimitReached, nil, ""})
+	}
+
+	for i, c := range children {
+		if c.err == nil {
+			if i == len(children)-1 {
+				_, _ = io.WriteString(w, restPrefix+treeLast+c.label+"\n")
+			} else {
+				_, _ = io.WriteString(w, restPrefix+treeBranch+c.label+"\n")
+			}
+			continue
+		}
+		if i == len(children)-1 {
+			o.formatTree(w, restPrefix+treeLast, restPrefix+treeSpace, c.label, depth+1, c.err, v, c.path)
+		} else {
+			o.formatTree(w, restPrefix+treeBranch, restPrefix+treeVertical, c.label, depth+1, c.err, v, c.path)
+		}
+	}
+}
+
+// writeTreeLines writes lines of formatted error s to w. The first line
+// is prefixed with firstPrefix and label, other lines with restPrefix.
+func writeTreeLines(w io.Writer, firstPrefix, restPrefix, label, s string) {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	for i, line := range lines {
+		if i == 0 {
+			_, _ = io.WriteString(w, firstPrefix+label+line+"\n")
+		} else {
+			_, _ = io.WriteString(w, strings.TrimRight(restPrefix+line, " ")+"\n")
+		}
+	}
+}
+
+func (o FormatOptions) formatMsg(w io.Writer, linePrefix string, err error) {
+	getMessage := o.GetMessage
 	if getMessage == nil {
 		getMessage = defaultGetMessage
 	}
 
-	writeLinesPrefixed(w, linePrefix, getMessage(err))
+	writeLinesPrefixed(w, linePrefix, o.colorLines(colorMessage, truncateMessage(getMessage(err), GetLimits().MaxMessageLength)))
 }
 
 // Similar to writeFields in zerolog/console.go.
-func (f Formatter) formatDetails(w io.Writer, linePrefix string, details map[string]interface{}) {
+func (o FormatOptions) formatDetails(w io.Writer, linePrefix string, err error, details map[string]interface{}, visited *visitedErrors) {
+	details, omitted := truncateDetails(details, GetLimits().MaxDetails)
+	omitted += getOmittedParts(err).details
 	fields := make([]string, len(details))
 	i := 0
 	for field := range details {
@@ -254,18 +473,50 @@ func (f Formatter) formatDetails(w io.Writer, linePrefix string, details map[str
 		case json.Number:
 			v = string(tValue)
 		default:
-			b, err := marshalWithoutEscapeHTML(tValue)
-			if err != nil {
-				v = fmt.Sprintf("[error: %v]", err)
-			} else {
-				v = string(b)
-			}
+			v = formatDetailValue(tValue, visited, field)
+		}
+		writeLinesPrefixed(w, linePrefix, fmt.Sprintf("%s=%s\n", o.colorLines(colorKey, field), o.colorLines(colorValue, v)))
+	}
+	if omitted > 0 {
+		writeLinesPrefixed(w, linePrefix, moreDetails(omitted))
+	}
+}
+
+// marshalDetailValue marshals value of the detail field as JSON,
+// recovering from a panic in its MarshalJSON method.
+func marshalDetailValue(value interface{}) (b []byte, err error) { //nolint:nonamedreturns
+	method := "MarshalJSON"
+	defer recoverPanic(&method, func(msg string) {
+		b, err = marshalWithoutEscapeHTML(msg)
+	})
+	return marshalWithoutEscapeHTML(value)
+}
+
+// formatDetailValue formats value of the detail field as JSON.
+// Errors (also inside maps and slices) already on the path from the root
+// are formatted as back-references to prevent infinite recursion.
+func formatDetailValue(value interface{}, visited *visitedErrors, field string) string {
+	er, ok := value.(error)
+	if ok {
+		ref, ok := visited.backReference(er)
+		if ok {
+			return ref
 		}
-		writeLinesPrefixed(w, linePrefix, fmt.Sprintf("%s=%s\n", field, v))
 	}
+	// We use a copy of visited so that marshaling does not change numbering of errors.
+	v := &visitedErrors{path: visited.path[:len(visited.path):len(visited.path)], count: visited.count, rewriteFrame: visited.rewriteFrame}
+	value, errE := jsonDetailValue(value, len(visited.path), v, visited.current()+"/"+escapeJSONPointer(field))
+	if errE != nil {
+		return fmt.Sprintf("[error: %v]", errE)
+	}
+	b, err := marshalDetailValue(value)
+	if err != nil {
+		return fmt.Sprintf("[error: %v]", err)
+	}
+	return string(b)
 }
 
-func (f Formatter) formatStack(s fmt.State, w io.Writer, linePrefix string, err error) {
+func (o FormatOptions) formatStack(w io.Writer, linePrefix string, err error) {
 	var stToFormat interface{}
 	st := getExistingStackTrace(err)
 	if len(st) > 0 {
@@ -282,17 +533,213 @@ func (f Formatter) formatStack(s fmt.State, w io.Writer, linePrefix string, err
 		stToFormat = placeholderSt
 	}
 
-	if s.Flag('-') {
+	stToFormat = rewriteStack(stToFormat, o.RewriteFrame)
+
+	if o.Help {
 		writeLinesPrefixed(w, linePrefix, stackTraceHelp)
 	}
 	var result string
-	width, ok := s.Width()
-	if ok {
-		result = fmt.Sprintf("%+*v", width, stToFormat)
+	if o.Indent > 0 {
+		result = fmt.Sprintf("%+*v", o.Indent, stToFormat)
 	} else {
 		result = fmt.Sprintf("%+v", stToFormat)
 	}
-	writeLinesPrefixed(w, linePrefix, result)
+	writeLinesPrefixed(w, linePrefix, o.colorStack(result))
+}
+
+func (o FormatOptions) formatBoundaries(w io.Writer, linePrefix string, err error) {
+	for _, boundary := range getBoundariesToFormat(err) {
+		boundary = rewriteStack(boundary, o.RewriteFrame)
+		if o.Help {
+			writeLinesPrefixed(w, linePrefix, boundaryHelp)
+		}
+		var result string
+		if o.Indent > 0 {
+			result = fmt.Sprintf("%+*v", o.Indent, boundary)
+		} else {
+			result = fmt.Sprintf("%+v", boundary)
+		}
+		writeLinesPrefixed(w, linePrefix, o.colorStack(result))
+	}
+}
+
+func (o FormatOptions) formatReturnTrace(w io.Writer, linePrefix string, err error) {
+	rtToFormat := getReturnTraceToFormat(err)
+	if rtToFormat == nil {
+		return
+	}
+	rtToFormat = rewriteStack(rtToFormat, o.RewriteFrame)
+
+	if o.Help {
+		writeLinesPrefixed(w, linePrefix, returnTraceHelp)
+	}
+	var result string
+	if o.Indent > 0 {
+		result = fmt.Sprintf("%+*v", o.Indent, rtToFormat)
+	} else {
+		result = fmt.Sprintf("%+v", rtToFormat)
+	}
+	writeLinesPrefixed(w, linePrefix, o.colorStack(result))
+	omitted := getReturnTraceOmitted(err)
+	if omitted > 0 {
+		writeLinesPrefixed(w, linePrefix, moreReturnPoints(omitted))
+	}
+}
+
+// FormatMode is the mode of operation of formatting, controlling
+// recursion into error causes and joined errors.
+type FormatMode int
+
+// Modes of operation of formatting. They correspond
+// to precision modes of Formatter.
+const (
+	// Do not recurse into error causes and joined errors.
+	FormatDefault FormatMode = iota
+	// Recurse into error causes and joined errors.
+	FormatRecursive
+	// Prefer error's fmt.Formatter interface implementation if error implements it.
+	FormatPreferFormatter
+	// Recurse into error causes and joined errors, but prefer fmt.Formatter
+	// interface implementation if any error implements it.
+	FormatRecursivePreferFormatter
+	// Recurse into error causes and joined errors, drawing the tree of errors.
+	FormatTree
+	// Recurse into error causes and joined errors, formatting all of them
+	// on a single line of key=value pairs (logfmt).
+	FormatLogfmt
+)
+
+// FormatOptions control how Format formats an error as text.
+//
+// Formatter maps fmt flags, width, and precision onto these options.
+type FormatOptions struct {
+	// List details as key=value lines after the error message, when available.
+	// Corresponds to the '#' flag.
+	Details bool `exhaustruct:"optional"`
+
+	// Follow with the formatted stack trace and boundary stack traces (see
+	// Rethrow), if available. Corresponds to the '+' flag.
+	Stack bool `exhaustruct:"optional"`
+
+	// Follow with the formatted return trace (see Trace), if available.
+	// Corresponds to the '0' flag. Unlike with flags, it can be combined
+	// with Help on all Go versions.
+	ReturnTrace bool `exhaustruct:"optional"`
+
+	// Add human friendly messages to delimit parts of the text.
+	// Corresponds to the '-' flag.
+	Help bool `exhaustruct:"optional"`
+
+	// Add extra newlines to separate parts of the text better.
+	// Corresponds to the ' ' flag.
+	Spacing bool `exhaustruct:"optional"`
+
+	// Mode of operation. Corresponds to the precision.
+	Mode FormatMode `exhaustruct:"optional"`
+
+	// The width of the indent step in spaces. Zero indents with a tab step.
+	// It is passed through to the stack trace formatting.
+	// Corresponds to the width.
+	Indent int `exhaustruct:"optional"`
+
+	// The maximum depth of causes and joined errors to recurse into.
+	// Zero means no limit.
+	MaxDepth int `exhaustruct:"optional"`
+
+	// The maximum number of joined errors to format for each error.
+	// Zero means no limit.
+	MaxJoined int `exhaustruct:"optional"`
+
+	// Provide a function to obtain the error's message.
+	// By default error's Error() is called.
+	GetMessage func(error) string `exhaustruct:"optional"`
+
+	// Color parts of the text using ANSI escape codes.
+	// It is ignored when the NO_COLOR environment variable is set.
+	// Format ignores it also when the writer is not a terminal
+	// (see ColorEnabled), while Sprint does not have a writer to check.
+	Color bool `exhaustruct:"optional"`
+
+	// Provide a function to rewrite stack frames before they are formatted
+	// (e.g., to make them deterministic in golden tests). It returns false
+	// to omit the frame. By default frames are formatted as they are.
+	RewriteFrame func(frame runtime.Frame) (runtime.Frame, bool) `exhaustruct:"optional"`
+}
+
+func (o FormatOptions) recursive() bool {
+	return o.Mode == FormatRecursive || o.Mode == FormatRecursivePreferFormatter
+}
+
+func (o FormatOptions) preferFormatter() bool {
+	return o.Mode == FormatPreferFormatter || o.Mode == FormatRecursivePreferFormatter
+}
+
+// formatString returns the fmt format string corresponding to o.
+func (o FormatOptions) formatString() string {
+	b := []byte{'%'}
+	for _, flag := range []struct {
+		set  bool
+		flag byte
+	}{{o.Spacing, ' '}, {o.Stack, '+'}, {o.Help, '-'}, {o.Details, '#'}, {o.ReturnTrace, '0'}} {
+		if flag.set {
+			b = append(b, flag.flag)
+		}
+	}
+	if o.Indent > 0 {
+		b = strconv.AppendInt(b, int64(o.Indent), 10)
+	}
+	if o.Mode != FormatDefault {
+		b = append(b, '.')
+		b = strconv.AppendInt(b, int64(o.Mode), 10)
+	}
+	b = append(b, 'v')
+	return string(b)
+}
+
+// Format formats err as text into w according to opts.
+//
+// It formats the error in the same way as Formatter does, but is
+// controlled by options instead of fmt flags, width, and precision.
+// It is assured that the text ends with a newline, if it does not already do so.
+//
+// Colors are used only if Color is set and ColorEnabled returns true for w.
+func Format(w io.Writer, err error, opts FormatOptions) E {
+	opts.Color = opts.Color && ColorEnabled(w)
+	buf := new(bytes.Buffer)
+	opts.formatError(buf, 0, 0, err, &visitedErrors{rewriteFrame: opts.RewriteFrame}, "#")
+	_, e := w.Write(buf.Bytes())
+	if e != nil {
+		return WithStack(e)
+	}
+	return nil
+}
+
+// Sprint formats err as text according to opts and returns the resulting string.
+//
+// See Format for more information.
+func Sprint(err error, opts FormatOptions) string {
+	buf := new(bytes.Buffer)
+	opts.formatError(buf, 0, 0, err, &visitedErrors{rewriteFrame: opts.RewriteFrame}, "#")
+	return buf.String()
+}
+
+// options maps fmt flags, width, and precision onto format options.
+func (f Formatter) options(s fmt.State, precision int) FormatOptions {
+	width, _ := s.Width()
+	return FormatOptions{
+		Details:      s.Flag('#'),
+		Stack:        s.Flag('+'),
+		ReturnTrace:  s.Flag('0'),
+		Help:         s.Flag('-'),
+		Spacing:      s.Flag(' '),
+		Mode:         FormatMode(precision),
+		Indent:       width,
+		MaxDepth:     0,
+		MaxJoined:    0,
+		GetMessage:   f.GetMessage,
+		Color:        f.Color,
+		RewriteFrame: f.RewriteFrame,
+	}
 }
 
 func defaultGetMessage(err error) string {
@@ -300,12 +747,41 @@ func defaultGetMessage(err error) string {
 }
 
 // Formatter formats an error as text and marshals the error as JSON.
+//
+// Panics in methods of errors (e.g., Error called on a nil pointer receiver)
+// are recovered and formatted in place of the rest of the error in the same
+// way as fmt does (e.g., "%!v(PANIC=Error method: ...)"), or marshaled as
+// the "error" field of the error's JSON object.
 type Formatter struct {
 	Error error
 
 	// Provide a function to obtain the error's message.
 	// By default error's Error() is called.
 	GetMessage func(error) string `exhaustruct:"optional"`
+
+	// Output only information which is safe to show to end users.
+	// When enabled, only the error's public message (see PublicMessage)
+	// is formatted or marshaled, ignoring GetMessage and all flags and
+	// modes of operation.
+	Public bool `exhaustruct:"optional"`
+
+	// Add the error's fingerprint (see Fingerprint function) to JSON.
+	// It is added as the "fingerprint" field of the top-level JSON object
+	// and it is preserved by UnmarshalJSON. Formatting as text is not affected.
+	Fingerprint bool `exhaustruct:"optional"`
+
+	// Color parts of the text (messages, detail keys and values, function
+	// names, and file:line locations) using ANSI escape codes when formatting
+	// with flags or non-zero precision. It is ignored when the NO_COLOR
+	// environment variable is set. Formatter cannot determine if the writer
+	// is a terminal because it has access only to fmt.State, so use ColorEnabled
+	// to determine if the writer supports colors. JSON is not affected.
+	Color bool `exhaustruct:"optional"`
+
+	// Provide a function to rewrite stack frames before they are formatted
+	// or marshaled (e.g., to make them deterministic in golden tests).
+	// It returns false to omit the frame. By default frames are not rewritten.
+	RewriteFrame func(frame runtime.Frame) (runtime.Frame, bool) `exhaustruct:"optional"`
 }
 
 // Format formats the error as text according to the fmt.Formatter interface.
@@ -335,9 +811,13 @@ type Formatter struct {
 // The following flags for %v are supported:
 //
 //	'#'   list details as key=value lines after the error message, when available
-//	'+'   follow with the %+v formatted stack trace, if available
+//	'+'   follow with the %+v formatted stack trace and boundary stack
+//	      traces (see Rethrow), if available
 //	'-'   add human friendly messages to delimit parts of the text
 //	' '   add extra newlines to separate parts of the text better
+//	'0'   follow with the %+v formatted return trace (see Trace), if available;
+//	      before Go 1.23 fmt ignores the '0' flag when the '-' flag is used, too,
+//	      so use Format or Sprint with FormatOptions to combine them
 //
 // Precision is specified by a period followed by a decimal number and enable
 // modes of operation. The following modes are supported:
@@ -348,14 +828,31 @@ type Formatter struct {
 //	.3    recurse into error causes and joined errors, but prefer fmt.Formatter
 //	      interface implementation if any error implements it; this means that
 //	      recursion stops if error's formatter does not recurse
+//	.4    recurse into error causes and joined errors, drawing the tree of errors
+//	      with ├─, └─, and │ connectors; each error is labeled with its relation
+//	      to its parent (joined, with, or cause) and flags control what is shown
+//	      for each error
+//	.5    recurse into error causes and joined errors, formatting all of them
+//	      on a single line of key=value pairs (logfmt), e.g.,
+//	      error="..." key=value stack="f1@file:1;f2@file:2" cause.error="...";
+//	      keys of joined errors are prefixed with errors[N]. and of the cause
+//	      with cause.; keys and values are quoted when needed so that the line
+//	      can be parsed back with ParseLogfmt; '#', '+', and '0' flags control
+//	      what is included while other flags and the width are ignored
 //
 // When any flag or non-zero precision mode is used, it is assured that the text
 // ends with a newline, if it does not already do so.
+//
+// When Public is set, only the public message of the error is formatted
+// and flags and modes are ignored (except that the text still ends with a newline).
 func (f Formatter) Format(s fmt.State, verb rune) {
 	getMessage := f.GetMessage
 	if getMessage == nil {
 		getMessage = defaultGetMessage
 	}
+	if f.Public {
+		getMessage = PublicMessage
+	}
 
 	switch verb {
 	case 'v':
@@ -365,12 +862,17 @@ func (f Formatter) Format(s fmt.State, verb rune) {
 			// See: https://github.com/golang/go/issues/61913
 			precision = 0
 		}
-		if precision < 0 || precision > 3 {
+		if precision < 0 || precision > 5 {
 			_, _ = io.WriteString(s, badPrecString)
 			break
 		}
-		if s.Flag('#') || s.Flag('+') || s.Flag('-') || s.Flag(' ') || precision > 0 {
-			f.formatError(s, s, 0, f.Error)
+		if s.Flag('#') || s.Flag('+') || s.Flag('-') || s.Flag(' ') || s.Flag('0') || precision > 0 {
+			if f.Public {
+				writeLinesPrefixed(s, "", getMessage(f.Error))
+			} else {
+				opts := f.options(s, precision)
+				opts.formatError(s, 0, 0, f.Error, &visitedErrors{rewriteFrame: opts.RewriteFrame}, "#")
+			}
 			break
 		}
 		fallthrough
@@ -1,9 +1,11 @@
 package errors_test
 
 import (
+	"encoding/json"
 	stderrors "errors"
 	"fmt"
 	"io"
+	"runtime"
 	"strconv"
 	"strings"
 	"testing"
@@ -2120,3 +2122,142 @@ func TestGetMessage(t *testing.T) {
 	}})
 	assert.Equal(t, "XtestX", got)
 }
+
+func TestFormatTree(t *testing.T) {
+	t.Parallel()
+
+	err := errors.WrapWith(
+		errors.Join(errors.Wrap(errors.Base("base\nmultiline"), "wrap"), errors.Base("second")),
+		errors.WithDetails(errors.Base("with"), "key", 1),
+	)
+
+	tests := []struct {
+		format string
+		want   string
+	}{{
+		"%.4v",
+		"with\n" +
+			"├─ with: with\n" +
+			"└─ cause: wrap\n" +
+			"   second\n" +
+			"   ├─ joined: wrap\n" +
+			"   │  └─ cause: base\n" +
+			"   │     multiline\n" +
+			"   └─ joined: second\n",
+	}, {
+		"%#.4v",
+		"with\n" +
+			"├─ with: with\n" +
+			"│  key=1\n" +
+			"└─ cause: wrap\n" +
+			"   second\n" +
+			"   ├─ joined: wrap\n" +
+			"   │  └─ cause: base\n" +
+			"   │     multiline\n" +
+			"   └─ joined: second\n",
+	}, {
+		"%.6v",
+		"%!(BADPREC)",
+	}}
+
+	for k, tt := range tests {
+		tt := tt
+
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, fmt.Sprintf(tt.format, err))
+		})
+	}
+
+	got := fmt.Sprintf("%+.4v", errors.Wrap(errors.New("error"), "wrap"))
+	assert.Regexp(t, "^wrap\n"+
+		"gitlab.com/tozd/go/errors_test.TestFormatTree\n"+
+		"\t.+/format_test.go:\\d+\n"+
+		"(?s:.+)"+
+		"└─ cause: error\n"+
+		"   gitlab.com/tozd/go/errors_test.TestFormatTree\n"+
+		"   \t.+/format_test.go:\\d+\n", got)
+}
+
+func TestFormatOptions(t *testing.T) {
+	t.Parallel()
+
+	err := errors.Wrap(
+		errors.Join(errors.WithDetails(errors.Base("first"), "key", "value"), errors.Base("second"), errors.Base("third")),
+		"wrap",
+	)
+
+	tests := []struct {
+		format string
+		opts   errors.FormatOptions
+	}{
+		{"%#v", errors.FormatOptions{Details: true}},
+		{"% -+#.1v", errors.FormatOptions{Details: true, Stack: true, Help: true, Spacing: true, Mode: errors.FormatRecursive}},
+		{"%+-4.3v", errors.FormatOptions{Stack: true, Help: true, Indent: 4, Mode: errors.FormatRecursivePreferFormatter}},
+		{"%#.4v", errors.FormatOptions{Details: true, Mode: errors.FormatTree}},
+	}
+
+	for k, tt := range tests {
+		tt := tt
+
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, fmt.Sprintf(tt.format, err), errors.Sprint(err, tt.opts))
+
+			var buf strings.Builder
+			errE := errors.Format(&buf, err, tt.opts)
+			assert.NoError(t, errE)
+			assert.Equal(t, fmt.Sprintf(tt.format, err), buf.String())
+		})
+	}
+
+	assert.Equal(t, "wrap\n", errors.Sprint(err, errors.FormatOptions{}))
+	assert.Equal(t, "wrap\nfirst\nsecond\nthird\ndepth limit reached\n", errors.Sprint(err, errors.FormatOptions{Mode: errors.FormatRecursive, MaxDepth: 1}))
+	assert.Equal(t, "wrap\nfirst\nsecond\nthird\n\tfirst\n\tsecond\n\tand 1 more error\n", errors.Sprint(err, errors.FormatOptions{Mode: errors.FormatRecursive, MaxJoined: 2}))
+	assert.Equal(t, "wrap\n└─ cause: first\n   second\n   third\n   └─ depth limit reached\n", errors.Sprint(err, errors.FormatOptions{Mode: errors.FormatTree, MaxDepth: 1}))
+	assert.Equal(t, "XwrapX\n", errors.Sprint(err, errors.FormatOptions{GetMessage: func(err error) string {
+		return "X" + err.Error() + "X"
+	}}))
+}
+
+func TestFormatRewriteFrame(t *testing.T) {
+	t.Parallel()
+
+	// Keep only frames of this test, with a stable location.
+	rewrite := func(frame runtime.Frame) (runtime.Frame, bool) {
+		if frame.Function != "gitlab.com/tozd/go/errors_test.TestFormatRewriteFrame" {
+			return frame, false
+		}
+		frame.File = "test.go"
+		frame.Line = 1
+		return frame, true
+	}
+
+	err := errors.Rethrow(errors.Trace(errors.WithDetails(errors.New("error"), "inner", errors.New("inner"))))
+
+	assert.Equal(t, "error\n"+
+		"inner={\"error\":\"inner\",\"stack\":[{\"name\":\"gitlab.com/tozd/go/errors_test.TestFormatRewriteFrame\",\"file\":\"test.go\",\"line\":1}]}\n"+
+		"gitlab.com/tozd/go/errors_test.TestFormatRewriteFrame\n"+
+		"\ttest.go:1\n"+
+		"gitlab.com/tozd/go/errors_test.TestFormatRewriteFrame\n"+
+		"\ttest.go:1\n"+
+		"gitlab.com/tozd/go/errors_test.TestFormatRewriteFrame\n"+
+		"\ttest.go:1\n",
+		fmt.Sprintf("%+#0v", errors.Formatter{Error: err, RewriteFrame: rewrite}))
+	assert.Equal(t, fmt.Sprintf("%+#0v", errors.Formatter{Error: err, RewriteFrame: rewrite}),
+		errors.Sprint(err, errors.FormatOptions{Details: true, Stack: true, ReturnTrace: true, RewriteFrame: rewrite}))
+
+	data, e := json.Marshal(errors.Formatter{Error: err, RewriteFrame: rewrite})
+	assert.NoError(t, e)
+	frame := `[{"name":"gitlab.com/tozd/go/errors_test.TestFormatRewriteFrame","file":"test.go","line":1}]`
+	assert.Equal(t, `{"boundaries":[`+frame+`],"error":"error","inner":{"error":"inner","stack":`+frame+`},"return_trace":`+frame+`,"stack":`+frame+`}`, string(data))
+
+	assert.Equal(t, `error=error inner="{\"error\":\"inner\",\"stack\":[{\"name\":\"gitlab.com/tozd/go/errors_test.TestFormatRewriteFrame\",\"file\":\"test.go\",\"line\":1}]}" `+
+		`stack=gitlab.com/tozd/go/errors_test.TestFormatRewriteFrame@test.go:1 return_trace=gitlab.com/tozd/go/errors_test.TestFormatRewriteFrame@test.go:1`+"\n",
+		errors.Sprint(err, errors.FormatOptions{Details: true, Stack: true, ReturnTrace: true, Mode: errors.FormatLogfmt, RewriteFrame: rewrite}))
+
+	// Without RewriteFrame, frames are not rewritten.
+	assert.NotContains(t, fmt.Sprintf("%+v", errors.Formatter{Error: err}), "test.go:1")
+}
@@ -0,0 +1,21 @@
+// Package shared exposes functionality of the errors package to its
+// subpackages (e.g., sentry) without making it part of the public API.
+//
+// Functions are set by the errors package when it is initialized,
+// so the errors package has to be imported as well.
+package shared
+
+var (
+	// IsOwnError returns true if err is made by the errors package.
+	IsOwnError func(err error) bool //nolint:gochecknoglobals
+
+	// IsSubsumed returns true if there is no information missing if
+	// err is output and base, one of errors joined by err, is skipped.
+	// Formatting and marshaling errors skip such joined errors.
+	IsSubsumed func(err, base error) bool //nolint:gochecknoglobals
+
+	// IsInAppFunction returns true if function (a full function name)
+	// is not from the Go standard library. Fingerprint uses it to find
+	// in-app stack frames.
+	IsInAppFunction func(function string) bool //nolint:gochecknoglobals
+)
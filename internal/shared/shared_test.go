@@ -0,0 +1,48 @@
+package shared_test
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/errors/internal/shared"
+)
+
+func TestIsOwnError(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, shared.IsOwnError(errors.New("error")))
+	assert.True(t, shared.IsOwnError(errors.WithPublicMessage(errors.Base("error"), "public")))
+	assert.False(t, shared.IsOwnError(errors.Base("error")))
+	assert.False(t, shared.IsOwnError(&fs.PathError{Op: "open", Path: "/file", Err: fs.ErrNotExist}))
+}
+
+func TestIsSubsumed(t *testing.T) {
+	t.Parallel()
+
+	base := errors.Base("error")
+	assert.True(t, shared.IsSubsumed(errors.WrapWith(errors.New("cause"), base), base))
+	assert.True(t, shared.IsSubsumed(base, nil))
+	assert.False(t, shared.IsSubsumed(base, errors.Base("other")))
+	assert.False(t, shared.IsSubsumed(errors.Base("error"), errors.New("error")))
+	assert.False(t, shared.IsSubsumed(errors.Base("error"), errors.WithDetails(base, "key", "value")))
+}
+
+func TestIsInAppFunction(t *testing.T) {
+	t.Parallel()
+
+	for function, inApp := range map[string]bool{
+		"":                              false,
+		"runtime.goexit":                false,
+		"net/http.(*Server).Serve":      false,
+		"main.main":                     true,
+		"app_test.TestApp":              true,
+		"example.com/app.Run":           true,
+		"example.com/app/pkg.(*T).Run":  true,
+		"example.com/app/pkg_test.Test": true,
+	} {
+		assert.Equal(t, inApp, shared.IsInAppFunction(function), function)
+	}
+}
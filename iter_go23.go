@@ -0,0 +1,104 @@
+//go:build go1.23
+
+package errors
+
+import (
+	"iter"
+)
+
+// All returns an iterator over the tree of errors rooted at err,
+// in depth-first order, starting with err itself.
+//
+// The tree and the order are the same as used by Walk.
+// If err is nil, the iterator yields nothing.
+func All(err error) iter.Seq[error] {
+	return func(yield func(error) bool) {
+		Walk(err, func(node Node) WalkAction {
+			if !yield(node.Err) {
+				return WalkStop
+			}
+			return WalkContinue
+		})
+	}
+}
+
+// Tree returns an iterator over the tree of errors rooted at err,
+// in depth-first order, starting with err itself. It yields
+// the depth of each error in the tree together with the error.
+//
+// The tree and the order are the same as used by Walk.
+// If err is nil, the iterator yields nothing.
+func Tree(err error) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		Walk(err, func(node Node) WalkAction {
+			if !yield(node.Depth, node.Err) {
+				return WalkStop
+			}
+			return WalkContinue
+		})
+	}
+}
+
+// Chain returns an iterator over err and errors obtained by repeatedly
+// calling Unwrap on it. Errors wrapping multiple errors end the chain.
+//
+// If err is nil, the iterator yields nothing.
+func Chain(err error) iter.Seq[error] {
+	return func(yield func(error) bool) {
+		seen := []error{}
+		for err != nil && !isAncestor(err, seen) {
+			if !yield(err) {
+				return
+			}
+			seen = append(seen, err)
+			err = Unwrap(err)
+		}
+	}
+}
+
+// Causes returns an iterator over the cause of err, the cause
+// of the cause, and so on (see Cause). err itself is not yielded.
+//
+// If err is nil or does not have a cause, the iterator yields nothing.
+func Causes(err error) iter.Seq[error] {
+	return func(yield func(error) bool) {
+		seen := []error{}
+		for err != nil {
+			seen = append(seen, err)
+			err = Cause(err)
+			if err == nil || isAncestor(err, seen) {
+				return
+			}
+			if !yield(err) {
+				return
+			}
+		}
+	}
+}
+
+// AllOf returns an iterator over all errors in the tree of errors
+// rooted at err which match the type E, in depth-first order.
+//
+// It is the multi-valued counterpart of AsType: an error matches
+// if it is of type E, or if it has a method As(any) bool such that
+// As(target) returns true, where target is of type *E.
+//
+// The tree and the order are the same as used by Walk.
+// If err is nil, the iterator yields nothing.
+func AllOf[E error](err error) iter.Seq[E] {
+	return func(yield func(E) bool) {
+		Walk(err, func(node Node) WalkAction {
+			e, ok := node.Err.(E) //nolint:errorlint
+			if !ok {
+				x, ok2 := node.Err.(interface{ As(any) bool }) //nolint:inamedparam
+				if ok2 {
+					ok = x.As(&e)
+				}
+			}
+			if ok && !yield(e) {
+				return WalkStop
+			}
+			return WalkContinue
+		})
+	}
+}
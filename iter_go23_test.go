@@ -0,0 +1,109 @@
+//go:build go1.23
+
+package errors_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func messages(seq func(yield func(error) bool)) []string {
+	result := []string{}
+	for err := range seq {
+		result = append(result, err.Error())
+	}
+	return result
+}
+
+func TestAll(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{}, messages(errors.All(nil)))
+
+	err := errors.Join(errors.Wrap(io.EOF, "wrap"), fmt.Errorf("fmt: %w", io.ErrUnexpectedEOF))
+	assert.Equal(t, []string{
+		"wrap\nfmt: unexpected EOF",
+		"wrap",
+		"EOF",
+		"fmt: unexpected EOF",
+		"unexpected EOF",
+	}, messages(errors.All(err)))
+
+	// Stopping early.
+	result := []string{}
+	for e := range errors.All(err) {
+		result = append(result, e.Error())
+		if len(result) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"wrap\nfmt: unexpected EOF", "wrap"}, result)
+
+	depths := []int{}
+	for depth := range errors.Tree(err) {
+		depths = append(depths, depth)
+	}
+	assert.Equal(t, []int{0, 1, 2, 1, 2}, depths)
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{}, messages(errors.Chain(nil)))
+
+	err := fmt.Errorf("fmt: %w", errors.WithStack(io.EOF))
+	assert.Equal(t, []string{"fmt: EOF", "EOF", "EOF"}, messages(errors.Chain(err)))
+
+	// Chain ends at joined errors.
+	err = fmt.Errorf("fmt: %w", errors.Join(io.EOF, io.ErrUnexpectedEOF))
+	assert.Equal(t, []string{"fmt: EOF\nunexpected EOF", "EOF\nunexpected EOF"}, messages(errors.Chain(err)))
+
+	c := &cycleError{}
+	c.err = errors.WithStack(c)
+	assert.Equal(t, []string{"cycle", "cycle"}, messages(errors.Chain(c)))
+}
+
+func TestCauses(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{}, messages(errors.Causes(nil)))
+	assert.Equal(t, []string{}, messages(errors.Causes(errors.New("error"))))
+
+	err := errors.Wrap(errors.WithDetails(errors.Wrap(io.EOF, "first"), "key", "value"), "second")
+	assert.Equal(t, []string{"first", "EOF"}, messages(errors.Causes(err)))
+
+	// Placeholder errors.
+	data, errE := json.Marshal(err)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	placeholder, errE := errors.UnmarshalJSON(data)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []string{"first", "EOF"}, messages(errors.Causes(placeholder)))
+}
+
+func TestAllOf(t *testing.T) {
+	t.Parallel()
+
+	pathErr1 := &fs.PathError{Op: "open", Path: "a", Err: io.EOF}
+	pathErr2 := &fs.PathError{Op: "open", Path: "b", Err: io.EOF}
+	err := errors.Join(errors.Wrap(pathErr1, "wrap"), errors.WithStack(pathErr2))
+
+	paths := []string{}
+	for e := range errors.AllOf[*fs.PathError](err) {
+		paths = append(paths, e.Path)
+	}
+	assert.Equal(t, []string{"a", "b"}, paths)
+
+	count := 0
+	for range errors.AllOf[*fs.PathError](io.EOF) {
+		count++
+	}
+	assert.Equal(t, 0, count)
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 func marshalWithoutEscapeHTML(v interface{}) ([]byte, error) {
@@ -24,12 +26,18 @@ func marshalWithoutEscapeHTML(v interface{}) ([]byte, error) {
 
 // isSubsumedError returns true if there is no information missing if we
 // output just err and simply skip/ignore base.
-func isSubsumedError(err, base error) bool {
+func isSubsumedError(err, base error) (subsumed bool) { //nolint:nonamedreturns
 	// No error contains no information.
 	if base == nil {
 		return true
 	}
 
+	// Methods of foreign errors might panic. We then output base so that the panic is reported.
+	method := "Error"
+	defer recoverPanic(&method, func(string) {
+		subsumed = false
+	})
+
 	// Is error message the same?
 	if err.Error() != base.Error() {
 		return false
@@ -54,6 +62,18 @@ func isSubsumedError(err, base error) bool {
 		}
 	}
 
+	// Base does not have boundary stack traces or they are the same as err's.
+	baseBoundaries := getBoundariesToFormat(base)
+	if len(baseBoundaries) > 0 && !reflect.DeepEqual(getBoundariesToFormat(err), baseBoundaries) {
+		return false
+	}
+
+	// Base does not have a return trace or it is the same as err's.
+	baseReturnTrace := getReturnTraceToFormat(base)
+	if baseReturnTrace != nil && !reflect.DeepEqual(getReturnTraceToFormat(err), baseReturnTrace) {
+		return false
+	}
+
 	// There are no additional details, cause or joined errors.
 	d, c, j := allDetailsUntilCauseOrJoined(base)
 	return len(d) == 0 && c == nil && len(j) == 0
@@ -61,54 +81,136 @@ func isSubsumedError(err, base error) bool {
 
 // marshalJSONError marshals errors using interfaces.
 func marshalJSONError(err error) ([]byte, E) {
+	return marshalJSONErrorDepth(err, 0, &visitedErrors{}, "#")
+}
+
+// marshalJSONErrorDepth marshals errors using interfaces,
+// respecting limits (see SetLimits) at depth. Errors on the path
+// from the root are tracked in v to detect cycles and path is
+// JSON pointer to err, used for back-references to it.
+func marshalJSONErrorDepth(err error, depth int, v *visitedErrors, path string) (jsonErr []byte, errE E) { //nolint:nonamedreturns
+	v.push(err, path)
+	defer v.pop()
+
+	// Methods of foreign errors might panic. We then marshal the panic as the error.
+	method := "Details"
+	defer recoverPanic(&method, func(msg string) {
+		jsonErr, errE = marshalJSONPanic(msg)
+	})
+
+	l := GetLimits()
+	omitted := getOmittedParts(err)
+
 	details, cause, errs := allDetailsUntilCauseOrJoined(err)
+	details, omittedDetails := truncateDetails(details, l.MaxDetails)
+	omitted.details += omittedDetails
 
 	data := map[string]interface{}{}
 
 	// We start with details so that other "standard"
 	// fields can override conflicting fields from details.
 	for key, value := range details {
+		value, errE := jsonDetailValue(value, depth, v, path+"/"+escapeJSONPointer(key))
+		if errE != nil {
+			return nil, errE
+		}
 		data[key] = value
 	}
 
-	msg := err.Error()
+	method = "Error"
+	msg := truncateMessage(err.Error(), l.MaxMessageLength)
 	if msg != "" {
 		data["error"] = msg
 	}
 
+	f, ok := err.(fingerprinter) //nolint:errorlint
+	if ok {
+		fingerprint := f.Fingerprint()
+		if fingerprint != "" {
+			data["fingerprint"] = fingerprint
+		}
+	}
+
+	method = "StackTrace"
 	st := getExistingStackTrace(err)
 	if len(st) > 0 {
-		data["stack"] = StackFormatter{st}
+		data["stack"] = rewriteStack(StackFormatter{st}, v.rewriteFrame)
 	} else {
 		placeholderErr, ok := err.(placeholderStackTracer)
 		if ok {
 			placeholderSt := placeholderErr.StackTrace()
 			if len(placeholderSt) > 0 {
-				data["stack"] = placeholderSt
+				data["stack"] = rewriteStack(placeholderSt, v.rewriteFrame)
 			}
 		}
 	}
 
+	boundaries := getBoundariesToFormat(err)
+	if len(boundaries) > 0 {
+		for i, boundary := range boundaries {
+			boundaries[i] = rewriteStack(boundary, v.rewriteFrame)
+		}
+		data["boundaries"] = boundaries
+	}
+
+	returnTrace := getReturnTraceToFormat(err)
+	if returnTrace != nil {
+		data["return_trace"] = rewriteStack(returnTrace, v.rewriteFrame)
+		returnTraceOmitted := getReturnTraceOmitted(err)
+		if returnTraceOmitted > 0 {
+			data[jsonReturnTraceOmitted] = returnTraceOmitted
+		}
+	}
+
+	if omitted.details > 0 {
+		data[jsonDetailsOmitted] = omitted.details
+	}
+
+	joined := []error{}
 	for _, er := range errs {
 		// er should never be nil, but we still check.
 		// We also make sure we do not repeat cause here or repeat an error without any additional information.
 		if er != nil && er != cause && !isSubsumedError(err, er) { //nolint:errorlint,err113
-			jsonEr, e := marshalJSONAnyError(er)
-			if e != nil {
-				return nil, e
-			}
-			if len(jsonEr) != 0 && !bytes.Equal(jsonEr, []byte("{}")) {
-				if data["errors"] == nil {
-					data["errors"] = []json.RawMessage{json.RawMessage(jsonEr)}
-				} else {
-					data["errors"] = append(data["errors"].([]json.RawMessage), json.RawMessage(jsonEr)) //nolint:forcetypeassert,errcheck
-				}
+			joined = append(joined, er)
+		}
+	}
+
+	if omitted.depthLimit || l.MaxDepth > 0 && depth >= l.MaxDepth && (cause != nil || len(joined) > 0 || omitted.errors > 0) {
+		data[jsonDepthLimitReached] = true
+		joined = nil
+		cause = nil
+		omitted.errors = 0
+	}
+
+	if l.MaxJoined > 0 && len(joined) > l.MaxJoined {
+		omitted.errors += len(joined) - l.MaxJoined
+		joined = joined[:l.MaxJoined]
+	}
+
+	for _, er := range joined {
+		erPath := path + "/errors/0"
+		if data["errors"] != nil {
+			erPath = path + "/errors/" + strconv.Itoa(len(data["errors"].([]json.RawMessage))) //nolint:forcetypeassert,errcheck
+		}
+		jsonEr, e := marshalJSONAnyErrorDepth(er, depth+1, v, erPath)
+		if e != nil {
+			return nil, e
+		}
+		if len(jsonEr) != 0 && !bytes.Equal(jsonEr, []byte("{}")) {
+			if data["errors"] == nil {
+				data["errors"] = []json.RawMessage{json.RawMessage(jsonEr)}
+			} else {
+				data["errors"] = append(data["errors"].([]json.RawMessage), json.RawMessage(jsonEr)) //nolint:forcetypeassert,errcheck
 			}
 		}
 	}
 
+	if omitted.errors > 0 {
+		data[jsonErrorsOmitted] = omitted.errors
+	}
+
 	if cause != nil {
-		jsonCause, e := marshalJSONAnyError(cause)
+		jsonCause, e := marshalJSONAnyErrorDepth(cause, depth+1, v, path+"/cause")
 		if e != nil {
 			return nil, e
 		}
@@ -117,6 +219,8 @@ func marshalJSONError(err error) ([]byte, E) {
 		}
 	}
 
+	// Details might contain values with MarshalJSON method.
+	method = "MarshalJSON"
 	jsonErr, e := marshalWithoutEscapeHTML(data)
 	if e != nil {
 		return nil, WithStack(e)
@@ -124,6 +228,60 @@ func marshalJSONError(err error) ([]byte, E) {
 	return jsonErr, nil
 }
 
+// marshalJSONPanic marshals an error object with msg describing a panic.
+func marshalJSONPanic(msg string) ([]byte, E) {
+	jsonErr, e := marshalWithoutEscapeHTML(map[string]string{"error": msg})
+	if e != nil {
+		return nil, WithStack(e)
+	}
+	return jsonErr, nil
+}
+
+// jsonDetailValue returns value of a detail with errors (also inside
+// maps and slices) on the path from the root replaced with back-references,
+// and errors made by this package marshaled with v, so that cycles through
+// details are detected as well. Path is JSON pointer to value.
+func jsonDetailValue(value interface{}, depth int, v *visitedErrors, path string) (interface{}, E) {
+	switch tValue := value.(type) {
+	case error:
+		if e, ok := v.find(tValue); ok {
+			ref, errE := marshalJSONRef(e.path)
+			return json.RawMessage(ref), errE
+		}
+		if !isOwnError(tValue) {
+			return value, nil
+		}
+		jsonErr, errE := marshalJSONAnyErrorDepth(tValue, depth+1, v, path)
+		return json.RawMessage(jsonErr), errE
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(tValue))
+		for key, val := range tValue {
+			val, errE := jsonDetailValue(val, depth, v, path+"/"+escapeJSONPointer(key))
+			if errE != nil {
+				return nil, errE
+			}
+			result[key] = val
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(tValue))
+		for i, val := range tValue {
+			val, errE := jsonDetailValue(val, depth, v, path+"/"+strconv.Itoa(i))
+			if errE != nil {
+				return nil, errE
+			}
+			result[i] = val
+		}
+		return result, nil
+	}
+	return value, nil
+}
+
+// escapeJSONPointer escapes key to be used as a JSON pointer reference token.
+func escapeJSONPointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
 func hasJSONTag(typ reflect.Type) bool {
 	if typ.Kind() == reflect.Struct {
 		for i := 0; i < typ.NumField(); i++ {
@@ -162,24 +320,42 @@ func useMarshaler(err error) bool {
 
 // marshalJSONAnyError marshals our and foreign errors.
 func marshalJSONAnyError(err error) ([]byte, E) {
+	return marshalJSONAnyErrorDepth(err, 0, &visitedErrors{}, "#")
+}
+
+// marshalJSONAnyErrorDepth marshals our and foreign errors at depth.
+// If err is already on the path from the root, a back-reference
+// to it is marshaled instead.
+func marshalJSONAnyErrorDepth(err error, depth int, v *visitedErrors, path string) (jsonErr []byte, errE E) { //nolint:nonamedreturns
 	if err == nil {
 		return []byte("null"), nil
 	}
 
+	if e, ok := v.find(err); ok {
+		return marshalJSONRef(e.path)
+	}
+
+	// Methods of foreign errors might panic. We then marshal the panic as the error.
+	method := "Unwrap"
+	defer recoverPanic(&method, func(msg string) {
+		jsonErr, errE = marshalJSONPanic(msg)
+	})
+
 	// This short-circuits our errors as well to directly call marshalJSONError
 	// and do not call it indirectly through marshalWithoutEscapeHTML.
 	if !useMarshaler(err) {
-		return marshalJSONError(err)
+		return marshalJSONErrorDepth(err, depth, v, path)
 	}
 
 	// Does the error marshal to something useful?
+	method = "MarshalJSON"
 	jsonErr, e := marshalWithoutEscapeHTML(err)
 	if e != nil {
 		return nil, WithStack(e)
 	}
 	if len(jsonErr) == 0 || bytes.Equal(jsonErr, []byte("{}")) {
 		// No it does not, we call marshalJSONError.
-		return marshalJSONError(err)
+		return marshalJSONErrorDepth(err, depth, v, path)
 	}
 
 	// It does, we return it.
@@ -198,6 +374,67 @@ func marshalJSONAnyError(err error) ([]byte, E) {
 // this function does as they implement json.Marshaler interface.
 // If you are not sure about the source of the error, it is safe to call this function
 // on them as well.
+//
+// When Public is set, JSON consists only of an object with the public message of
+// the error (see PublicMessage) in the "error" field.
+//
+// When Fingerprint is set, the fingerprint of the error (see Fingerprint function)
+// is added as the "fingerprint" field to the top-level JSON object.
+//
+// When RewriteFrame is set, frames of stack traces are rewritten with it.
+// Stack traces of errors which marshal themselves are not rewritten.
 func (f Formatter) MarshalJSON() ([]byte, error) {
-	return marshalJSONAnyError(f.Error)
+	var jsonErr []byte
+	var errE E
+	if f.Public {
+		jsonErr, errE = marshalJSONPublicError(f.Error)
+	} else {
+		jsonErr, errE = marshalJSONAnyErrorDepth(f.Error, 0, &visitedErrors{rewriteFrame: f.RewriteFrame}, "#")
+	}
+	if errE != nil {
+		return nil, errE
+	}
+	if f.Fingerprint && f.Error != nil {
+		return addJSONFingerprint(jsonErr, Fingerprint(f.Error))
+	}
+	return jsonErr, nil
+}
+
+// addJSONFingerprint adds the "fingerprint" field to JSON object jsonErr.
+func addJSONFingerprint(jsonErr []byte, fingerprint string) ([]byte, E) {
+	var data map[string]json.RawMessage
+	e := json.Unmarshal(jsonErr, &data)
+	if e != nil {
+		// Error marshaled itself into something which is not a JSON object.
+		return jsonErr, nil
+	}
+	jsonFingerprint, e := marshalWithoutEscapeHTML(fingerprint)
+	if e != nil {
+		return nil, WithStack(e)
+	}
+	data["fingerprint"] = json.RawMessage(jsonFingerprint)
+	jsonErr, e = marshalWithoutEscapeHTML(data)
+	if e != nil {
+		return nil, WithStack(e)
+	}
+	return jsonErr, nil
+}
+
+// marshalJSONPublicError marshals only the public message of the error.
+func marshalJSONPublicError(err error) ([]byte, E) {
+	if err == nil {
+		return []byte("null"), nil
+	}
+
+	data := map[string]interface{}{}
+	msg := PublicMessage(err)
+	if msg != "" {
+		data["error"] = msg
+	}
+
+	jsonErr, e := marshalWithoutEscapeHTML(data)
+	if e != nil {
+		return nil, WithStack(e)
+	}
+	return jsonErr, nil
 }
@@ -0,0 +1,156 @@
+package errors
+
+import (
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Summaries of omitted parts of errors.
+const (
+	depthLimitReached = "depth limit reached"
+)
+
+// JSON fields used to summarize omitted parts of errors.
+const (
+	jsonErrorsOmitted     = "errors_omitted"
+	jsonDetailsOmitted    = "details_omitted"
+	jsonDepthLimitReached = "depth_limit_reached"
+	// Not a limit set by SetLimits, see Trace.
+	jsonReturnTraceOmitted = "return_trace_omitted"
+)
+
+// Limits limit how much of huge trees of errors is included in error
+// messages, formatted text, and JSON. Zero value of a field means no limit.
+//
+// Omitted parts are summarized: in text with lines like "and 9,990 more errors"
+// or "depth limit reached", and in JSON with "errors_omitted", "details_omitted",
+// and "depth_limit_reached" fields. UnmarshalJSON reconstructs those summaries
+// so that placeholder errors are formatted and marshaled the same.
+type Limits struct {
+	// The maximum depth of causes and joined errors to recurse into
+	// when formatting and marshaling errors.
+	MaxDepth int `exhaustruct:"optional"`
+
+	// The maximum number of joined errors included for each error,
+	// also in messages of errors made by Join.
+	MaxJoined int `exhaustruct:"optional"`
+
+	// The maximum length of error messages in bytes when
+	// formatting and marshaling errors, and of messages
+	// of errors made by Join.
+	MaxMessageLength int `exhaustruct:"optional"`
+
+	// The maximum number of details included for each error
+	// when formatting and marshaling errors.
+	MaxDetails int `exhaustruct:"optional"`
+}
+
+var limits atomic.Value //nolint:gochecknoglobals
+
+// SetLimits sets limits used by this package.
+// By default there are no limits.
+//
+// Limits are applied when errors are formatted or marshaled.
+// The message of an error made by Join is computed when the
+// error is made, so limits are applied to it at that time.
+func SetLimits(l Limits) {
+	limits.Store(l)
+}
+
+// GetLimits returns limits used by this package.
+func GetLimits() Limits {
+	l, ok := limits.Load().(Limits)
+	if !ok {
+		return Limits{}
+	}
+	return l
+}
+
+// omittedParts summarizes parts of an error omitted because of limits.
+type omittedParts struct {
+	errors      int
+	details     int
+	depthLimit  bool
+	returnTrace int
+}
+
+type placeholderOmitter interface {
+	OmittedParts() omittedParts
+}
+
+// getOmittedParts returns parts omitted from a placeholder error
+// when it was marshaled.
+func getOmittedParts(err error) omittedParts {
+	o, ok := err.(placeholderOmitter) //nolint:errorlint
+	if ok {
+		return o.OmittedParts()
+	}
+	return omittedParts{}
+}
+
+// truncateMessage truncates msg to at most maxLength bytes (on a rune boundary),
+// summarizing the number of omitted bytes.
+func truncateMessage(msg string, maxLength int) string {
+	if maxLength <= 0 || len(msg) <= maxLength {
+		return msg
+	}
+	i := maxLength
+	for i > 0 && !utf8.RuneStart(msg[i]) {
+		i--
+	}
+	return msg[:i] + "... (" + formatCount(len(msg)-i) + " more bytes)"
+}
+
+// truncateDetails returns at most maxDetails details (ordered by key)
+// and the number of omitted details.
+func truncateDetails(details map[string]interface{}, maxDetails int) (map[string]interface{}, int) {
+	if maxDetails <= 0 || len(details) <= maxDetails {
+		return details, 0
+	}
+	keys := make([]string, 0, len(details))
+	for key := range details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make(map[string]interface{}, maxDetails)
+	for _, key := range keys[:maxDetails] {
+		result[key] = details[key]
+	}
+	return result, len(details) - maxDetails
+}
+
+func moreErrors(n int) string {
+	if n == 1 {
+		return "and 1 more error"
+	}
+	return "and " + formatCount(n) + " more errors"
+}
+
+func moreDetails(n int) string {
+	if n == 1 {
+		return "and 1 more detail"
+	}
+	return "and " + formatCount(n) + " more details"
+}
+
+func moreReturnPoints(n int) string {
+	if n == 1 {
+		return "and 1 earlier return point"
+	}
+	return "and " + formatCount(n) + " earlier return points"
+}
+
+// formatCount formats n with thousands separators.
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	start := 0
+	if n < 0 {
+		start = 1
+	}
+	for i := len(s) - 3; i > start; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
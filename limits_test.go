@@ -0,0 +1,91 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func TestLimits(t *testing.T) { //nolint:paralleltest
+	// Limits are global so we cannot use t.Parallel.
+	t.Cleanup(func() {
+		errors.SetLimits(errors.Limits{})
+	})
+
+	errs := make([]error, 10000)
+	for i := range errs {
+		errs[i] = errors.Base(fmt.Sprintf("error %d", i))
+	}
+
+	errors.SetLimits(errors.Limits{MaxJoined: 10, MaxMessageLength: 15})
+	assert.Equal(t, errors.Limits{MaxJoined: 10, MaxMessageLength: 15}, errors.GetLimits())
+	assert.Equal(t, "error 0\nerror 1... (86 more bytes)", errors.Join(errs...).Error())
+
+	errors.SetLimits(errors.Limits{MaxJoined: 10})
+	assert.Equal(t, "error 0\nerror 1\nerror 2\nerror 3\nerror 4\nerror 5\nerror 6\nerror 7\nerror 8\nerror 9\nand 9,990 more errors", errors.Join(errs...).Error())
+
+	errors.SetLimits(errors.Limits{MaxJoined: 2})
+	joined := errors.Join(errs...)
+	assert.Equal(t, "error 0\nerror 1\nand 9,998 more errors", joined.Error())
+	err := errors.Wrap(errors.WithDetails(joined, "a", 1, "b", 2, "c", 3), strings.Repeat("x", 20))
+
+	errors.SetLimits(errors.Limits{MaxJoined: 2, MaxMessageLength: 15, MaxDetails: 2})
+	want := "xxxxxxxxxxxxxxx... (5 more bytes)\n" +
+		"error 0\nerror 1... (22 more bytes)\n" +
+		"a=1\n" +
+		"b=2\n" +
+		"and 1 more detail\n" +
+		"\terror 0\n" +
+		"\terror 1\n" +
+		"\tand 9,998 more errors\n"
+	assert.Equal(t, want, fmt.Sprintf("%#.1v", err))
+
+	data, e := json.Marshal(err)
+	require.NoError(t, e)
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &payload))
+	cause, ok := payload["cause"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, 9998.0, cause["errors_omitted"])
+	assert.Equal(t, 1.0, cause["details_omitted"])
+	assert.Len(t, cause["errors"], 2)
+
+	errors.SetLimits(errors.Limits{MaxDepth: 1})
+	assert.Equal(t, "xxxxxxxxxxxxxxxxxxxx\nerror 0\nerror 1\nand 9,998 more errors\ndepth limit reached\n", fmt.Sprintf("%.1v", err))
+
+	data, e = json.Marshal(err)
+	require.NoError(t, e)
+	payload = nil
+	require.NoError(t, json.Unmarshal(data, &payload))
+	cause, ok = payload["cause"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, true, cause["depth_limit_reached"])
+	assert.NotContains(t, cause, "errors")
+
+	// Summaries are reconstructed by UnmarshalJSON.
+	errors.SetLimits(errors.Limits{MaxJoined: 2, MaxDetails: 2})
+	data, e = json.Marshal(err)
+	require.NoError(t, e)
+	formatted := fmt.Sprintf("%#.1v", err)
+	errors.SetLimits(errors.Limits{})
+
+	placeholder, errE := errors.UnmarshalJSON(data)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	data2, e := json.Marshal(placeholder)
+	require.NoError(t, e)
+	assert.JSONEq(t, string(data), string(data2))
+	assert.Equal(t, formatted, fmt.Sprintf("%#.1v", placeholder))
+	assert.Contains(t, fmt.Sprintf("%#.4v", placeholder), "   ├─ joined: error 1\n   └─ and 9,998 more errors\n")
+
+	data, e = json.Marshal(errors.Join(errors.Base("a"), errors.Base("b")))
+	require.NoError(t, e)
+	placeholder, errE = errors.UnmarshalJSON([]byte(strings.Replace(string(data), `"error"`, `"depth_limit_reached":true,"error"`, 1)))
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, "a\nb\ndepth limit reached\n", fmt.Sprintf("%.1v", placeholder))
+}
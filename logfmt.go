@@ -0,0 +1,420 @@
+package errors
+
+import (
+	"encoding/json"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Prefixes of keys of causes and joined errors in logfmt.
+const (
+	logfmtCausePrefix  = "cause."
+	logfmtErrorsPrefix = "errors["
+)
+
+// logfmtReserved are keys used for the error's own data in logfmt.
+// Details with these keys are not formatted, in the same way as
+// such details are overridden when marshaling the error as JSON.
+var logfmtReserved = map[string]bool{ //nolint:gochecknoglobals
+	"error":                true,
+	"stack":                true,
+	"return_trace":         true,
+	jsonRef:                true,
+	jsonErrorsOmitted:      true,
+	jsonDetailsOmitted:     true,
+	jsonDepthLimitReached:  true,
+	jsonReturnTraceOmitted: true,
+	"boundaries":           true,
+	"fingerprint":          true,
+}
+
+// formatLogfmt formats err and recurses into its joined errors and cause,
+// all on a single line of key=value pairs.
+func (o FormatOptions) formatLogfmt(w io.Writer, err error, v *visitedErrors, path string) {
+	fields := []string{}
+	o.logfmtFields(&fields, "", 0, err, v, path)
+	_, _ = io.WriteString(w, strings.Join(fields, " "))
+	_, _ = io.WriteString(w, "\n")
+}
+
+// logfmtFields appends key=value pairs of err to fields, prefixing keys with prefix.
+// Joined errors and the cause are appended recursively with longer prefixes.
+func (o FormatOptions) logfmtFields(fields *[]string, prefix string, depth int, err error, v *visitedErrors, path string) {
+	// An error already on the path from the root would make a cycle.
+	if e, ok := v.find(err); ok {
+		*fields = append(*fields, logfmtField(prefix+jsonRef, e.path))
+		return
+	}
+	r, ok := err.(*placeholderRefError) //nolint:errorlint
+	if ok {
+		*fields = append(*fields, logfmtField(prefix+jsonRef, r.ref))
+		return
+	}
+	v.push(err, path)
+	defer v.pop()
+
+	// Methods of foreign errors might panic. We format the panic in place of the rest of the error.
+	method := "Details"
+	defer recoverPanic(&method, func(msg string) {
+		*fields = append(*fields, logfmtField(prefix+"error", msg))
+	})
+
+	l := GetLimits()
+	omitted := getOmittedParts(err)
+	details, cause, errs := allDetailsUntilCauseOrJoined(err)
+
+	method = "Error"
+	getMessage := o.GetMessage
+	if getMessage == nil {
+		getMessage = defaultGetMessage
+	}
+	msg := truncateMessage(getMessage(err), l.MaxMessageLength)
+	if msg != "" {
+		*fields = append(*fields, logfmtField(prefix+"error", msg))
+	}
+
+	if o.Details {
+		method = "MarshalJSON"
+		details, omittedDetails := truncateDetails(details, l.MaxDetails)
+		omitted.details += omittedDetails
+		keys := make([]string, 0, len(details))
+		for key := range details {
+			if !logfmtReserved[key] {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			var value string
+			switch tValue := details[key].(type) {
+			case string:
+				value = logfmtString(tValue)
+			case json.Number:
+				value = string(tValue)
+			default:
+				value = formatDetailValue(tValue, v, key)
+			}
+			*fields = append(*fields, logfmtField(prefix+logfmtKey(key), value))
+		}
+	}
+
+	if o.Stack {
+		method = "StackTrace"
+		stack := logfmtStack(rewriteFrames(Frames(err), o.RewriteFrame))
+		if stack != "" {
+			*fields = append(*fields, logfmtField(prefix+"stack", stack))
+		}
+	}
+
+	if o.ReturnTrace {
+		trace := logfmtStack(rewriteFrames(returnTraceFrames(err), o.RewriteFrame))
+		if trace != "" {
+			*fields = append(*fields, logfmtField(prefix+"return_trace", trace))
+			returnTraceOmitted := getReturnTraceOmitted(err)
+			if returnTraceOmitted > 0 {
+				*fields = append(*fields, logfmtField(prefix+jsonReturnTraceOmitted, strconv.Itoa(returnTraceOmitted)))
+			}
+		}
+	}
+
+	if o.Details && omitted.details > 0 {
+		*fields = append(*fields, logfmtField(prefix+jsonDetailsOmitted, strconv.Itoa(omitted.details)))
+	}
+
+	method = "Unwrap"
+	joined, omittedErrors, depthLimit := o.joinedOrDepthLimit(depth, err, cause, errs)
+	if depthLimit {
+		cause = nil
+		*fields = append(*fields, logfmtField(prefix+jsonDepthLimitReached, "true"))
+	}
+
+	for i, er := range joined {
+		index := strconv.Itoa(i)
+		o.logfmtFields(fields, prefix+logfmtErrorsPrefix+index+"].", depth+1, er, v, path+"/errors/"+index)
+	}
+
+	if omittedErrors > 0 {
+		*fields = append(*fields, logfmtField(prefix+jsonErrorsOmitted, strconv.Itoa(omittedErrors)))
+	}
+
+	if cause != nil {
+		o.logfmtFields(fields, prefix+logfmtCausePrefix, depth+1, cause, v, path+"/cause")
+	}
+}
+
+// logfmtField formats key=value pair, quoting value if necessary.
+func logfmtField(key, value string) string {
+	if value == "" || needsQuote(value) || strings.ContainsRune(value, '=') {
+		value = strconv.Quote(value)
+	}
+	return key + "=" + value
+}
+
+// logfmtKey quotes a detail key if necessary, so that it can be parsed
+// back and is not confused with prefixes of the cause and joined errors.
+func logfmtKey(key string) string {
+	if key == "" || needsQuote(key) || strings.ContainsRune(key, '=') ||
+		strings.HasPrefix(key, logfmtCausePrefix) || strings.HasPrefix(key, logfmtErrorsPrefix) {
+		return strconv.Quote(key)
+	}
+	return key
+}
+
+// logfmtString returns a string detail value so that it is parsed back
+// as a string: if it is valid JSON (e.g., "42"), it is encoded as a JSON string.
+func logfmtString(s string) string {
+	if json.Valid([]byte(s)) {
+		data, err := marshalWithoutEscapeHTML(s)
+		if err == nil {
+			return string(data)
+		}
+	}
+	return s
+}
+
+// returnTraceFrames returns frames of err's return trace, supporting
+// placeholder errors as well. It returns nil if err does not have a return trace.
+func returnTraceFrames(err error) []runtime.Frame {
+	switch rt := getReturnTraceToFormat(err).(type) {
+	case StackFormatter:
+		return stackFrames(rt.Stack)
+	case placeholderStack:
+		return rt.frames()
+	}
+	return nil
+}
+
+// logfmtStack formats frames as function@file:line frames separated with semicolons.
+func logfmtStack(frames []runtime.Frame) string {
+	parts := make([]string, len(frames))
+	for i, f := range frames {
+		parts[i] = f.Function + "@" + f.File + ":" + strconv.Itoa(f.Line)
+	}
+	return strings.Join(parts, ";")
+}
+
+// parseLogfmtStack parses frames formatted by logfmtStack.
+func parseLogfmtStack(s string) ([]placeholderFrame, bool) {
+	frames := []placeholderFrame{}
+	for _, part := range strings.Split(s, ";") {
+		at := strings.LastIndex(part, "@")
+		if at < 0 {
+			return nil, false
+		}
+		colon := strings.LastIndex(part, ":")
+		if colon < at {
+			return nil, false
+		}
+		line, err := strconv.Atoi(part[colon+1:])
+		if err != nil {
+			return nil, false
+		}
+		frames = append(frames, placeholderFrame{
+			Name: part[:at],
+			File: part[at+1 : colon],
+			Line: line,
+		})
+	}
+	return frames, true
+}
+
+// logfmtNode collects fields of an error parsed from logfmt.
+type logfmtNode struct {
+	fields map[string]interface{}
+	cause  *logfmtNode
+	errors map[int]*logfmtNode
+}
+
+func newLogfmtNode() *logfmtNode {
+	return &logfmtNode{
+		fields: map[string]interface{}{},
+		cause:  nil,
+		errors: map[int]*logfmtNode{},
+	}
+}
+
+// payload returns the error as JSON payload, as accepted by UnmarshalJSON.
+func (n *logfmtNode) payload() map[string]interface{} {
+	payload := n.fields
+	if len(n.errors) > 0 {
+		indices := make([]int, 0, len(n.errors))
+		for i := range n.errors {
+			indices = append(indices, i)
+		}
+		sort.Ints(indices)
+		errs := make([]interface{}, len(indices))
+		for i, index := range indices {
+			errs[i] = n.errors[index].payload()
+		}
+		payload["errors"] = errs
+	}
+	if n.cause != nil {
+		payload["cause"] = n.cause.payload()
+	}
+	return payload
+}
+
+// parseLogfmtValue parses a value of key. Stack traces are parsed into frames
+// and values which are valid JSON into corresponding values (strings
+// which are valid JSON are encoded as JSON strings by logfmtString).
+// Error messages and back-references are always strings.
+func parseLogfmtValue(key, value string) interface{} {
+	switch key {
+	case "error", jsonRef:
+		return value
+	case "stack", "return_trace":
+		frames, ok := parseLogfmtStack(value)
+		if ok {
+			return frames
+		}
+		return value
+	}
+	if !json.Valid([]byte(value)) {
+		return value
+	}
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	var v interface{}
+	err := decoder.Decode(&v)
+	if err != nil {
+		return value
+	}
+	return v
+}
+
+// logfmtQuoteEnd returns the index of the closing quote of the quoted
+// string starting at i in line, or len(line) if it is unterminated.
+func logfmtQuoteEnd(line string, i int) int {
+	end := i + 1
+	for end < len(line) && line[end] != '"' {
+		if line[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end > len(line) {
+		return len(line)
+	}
+	return end
+}
+
+// splitLogfmt splits line into keys and (unquoted) values.
+// Keys are returned as they are, possibly with quoted parts.
+func splitLogfmt(line string) ([]string, []string, E) {
+	keys := []string{}
+	values := []string{}
+	i := 0
+	for {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		if i >= len(line) {
+			return keys, values, nil
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			if line[i] == '"' {
+				i = logfmtQuoteEnd(line, i)
+				if i >= len(line) {
+					return nil, nil, Errorf("unterminated key at position %d", start)
+				}
+			}
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			return nil, nil, Errorf("missing key at position %d", start)
+		}
+		if i >= len(line) || line[i] != '=' {
+			return nil, nil, Errorf(`missing value for key "%s"`, key)
+		}
+		i++
+		if i < len(line) && line[i] == '"' {
+			end := logfmtQuoteEnd(line, i)
+			if end >= len(line) {
+				return nil, nil, Errorf(`unterminated value for key "%s"`, key)
+			}
+			value, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, nil, WithStack(err)
+			}
+			keys = append(keys, key)
+			values = append(values, value)
+			i = end + 1
+		} else {
+			start = i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				i++
+			}
+			keys = append(keys, key)
+			values = append(values, line[start:i])
+		}
+	}
+}
+
+// ParseLogfmt parses a line of errors formatted in logfmt (with the .5 precision
+// mode, see Formatter) into placeholder errors, in the same way as UnmarshalJSON
+// unmarshals JSON errors (and with the same limitations).
+//
+// Keys prefixed with "cause." and "errors[N]." belong to the cause and joined
+// errors, respectively. The rest of the key can be quoted (e.g., when the key
+// contains spaces). Values which are valid JSON (e.g., numbers, JSON strings,
+// or JSON objects) are parsed as such, other values are kept as strings.
+//
+// If line is empty, ParseLogfmt returns nil.
+func ParseLogfmt(line string) (error, E) { //nolint:revive,staticcheck
+	keys, values, errE := splitLogfmt(strings.TrimRight(line, "\r\n"))
+	if errE != nil {
+		return nil, errE
+	}
+	if len(keys) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	root := newLogfmtNode()
+	for i, key := range keys {
+		node := root
+		for {
+			if strings.HasPrefix(key, logfmtCausePrefix) {
+				if node.cause == nil {
+					node.cause = newLogfmtNode()
+				}
+				node = node.cause
+				key = key[len(logfmtCausePrefix):]
+				continue
+			}
+			if strings.HasPrefix(key, logfmtErrorsPrefix) {
+				end := strings.Index(key, "].")
+				if end > 0 {
+					index, err := strconv.Atoi(key[len(logfmtErrorsPrefix):end])
+					if err == nil && index >= 0 {
+						if node.errors[index] == nil {
+							node.errors[index] = newLogfmtNode()
+						}
+						node = node.errors[index]
+						key = key[end+2:]
+						continue
+					}
+				}
+			}
+			break
+		}
+		if strings.HasPrefix(key, `"`) {
+			unquoted, err := strconv.Unquote(key)
+			if err != nil {
+				return nil, Errorf("invalid key %s", keys[i])
+			}
+			key = unquoted
+		}
+		node.fields[key] = parseLogfmtValue(key, values[i])
+	}
+
+	data, err := marshalWithoutEscapeHTML(root.payload())
+	if err != nil {
+		return nil, WithStack(err)
+	}
+	return UnmarshalJSON(data)
+}
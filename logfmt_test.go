@@ -0,0 +1,153 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func TestLogfmt(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Name   string
+		JSON   string
+		Format string
+		Logfmt string
+	}{
+		{
+			"simple",
+			`{"error":"simple"}`,
+			"%.5v",
+			"error=simple\n",
+		},
+		{
+			"details",
+			`{"error":"error with space","key":"value","number":42,"quoted":"a \"b\"","object":{"a":[1,2]}}`,
+			"%#.5v",
+			`error="error with space" key=value number=42 object="{\"a\":[1,2]}" quoted="a \"b\""` + "\n",
+		},
+		{
+			"stack",
+			`{"error":"error","stack":[{"name":"main.f1","file":"/a/file.go","line":1},{"name":"main.f2","file":"/a/file.go","line":2}]}`,
+			"%+.5v",
+			"error=error stack=main.f1@/a/file.go:1;main.f2@/a/file.go:2\n",
+		},
+		{
+			"cause",
+			`{"error":"wrap","key":"value","cause":{"error":"cause","cause":{"error":"root"}}}`,
+			"%#.5v",
+			"error=wrap key=value cause.error=cause cause.cause.error=root\n",
+		},
+		{
+			"joined",
+			`{"error":"a\nb","errors":[{"error":"a","key":1},{"error":"b","cause":{"error":"c"}}]}`,
+			"%#.5v",
+			`error="a\nb" errors[0].error=a errors[0].key=1 errors[1].error=b errors[1].cause.error=c` + "\n",
+		},
+		{
+			"keys",
+			`{"error":"e","":1,"a b":2,"a\"b":3,"a=b":4,"cause.x":5,"errors[0].y":6}`,
+			"%#.5v",
+			`error=e ""=1 "a b"=2 "a\"b"=3 "a=b"=4 "cause.x"=5 "errors[0].y"=6` + "\n",
+		},
+		{
+			"strings",
+			`{"error":"e","bool":"true","null":"null","number":"42","object":"{}","string":"\"quoted\"","text":"text"}`,
+			"%#.5v",
+			`error=e bool="\"true\"" null="\"null\"" number="\"42\"" object="\"{}\"" string="\"\\\"quoted\\\"\"" text=text` + "\n",
+		},
+		{
+			"cycle",
+			`{"error":"a","cause":{"error":"b","cause":{"$ref":"#"}}}`,
+			"%.5v",
+			"error=a cause.error=b cause.cause.$ref=#\n",
+		},
+		{
+			"omitted",
+			`{"error":"a","details_omitted":2,"errors_omitted":3,"errors":[{"error":"b","depth_limit_reached":true}]}`,
+			"%#.5v",
+			"error=a details_omitted=2 errors[0].error=b errors[0].depth_limit_reached=true errors_omitted=3\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+
+			placeholder, errE := errors.UnmarshalJSON([]byte(tt.JSON))
+			require.NoError(t, errE, "% -+#.1v", errE)
+			assert.Equal(t, tt.Logfmt, fmt.Sprintf(tt.Format, errors.Formatter{Error: placeholder}))
+
+			parsed, errE := errors.ParseLogfmt(tt.Logfmt)
+			require.NoError(t, errE, "% -+#.1v", errE)
+			data, e := json.Marshal(parsed)
+			require.NoError(t, e)
+			assert.JSONEq(t, tt.JSON, string(data))
+		})
+	}
+}
+
+func TestLogfmtRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	err := errors.WithDetails(
+		errors.Wrap(errors.Join(errors.Base("a"), errors.New("b")), "wrap"),
+		"key", "value",
+	)
+
+	line := fmt.Sprintf("%#+.5v", err)
+	assert.Regexp(t, `^error=wrap key=value stack=gitlab.com/tozd/go/errors_test.TestLogfmtRoundTrip@.+/logfmt_test.go:\d+;.* cause.error="a\\nb" cause.stack=.* cause.errors\[0\].error=a cause.errors\[1\].error=b cause.errors\[1\].stack=.*\n$`, line)
+
+	parsed, errE := errors.ParseLogfmt(line)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, fmt.Sprintf("%#+.1v", err), fmt.Sprintf("%#+.1v", parsed))
+	assert.Equal(t, line, fmt.Sprintf("%#+.5v", parsed))
+
+	assert.Equal(t, "error=x cause.error=x cause.errors[0].error=x cause.errors[1].error=x\n", fmt.Sprintf("%.5v", errors.Formatter{Error: err, GetMessage: func(error) string { return "x" }}))
+	assert.Equal(t, `error=wrap key=value cause.error="a\nb" cause.errors[0].error=a cause.errors[1].error=b`+"\n", errors.Sprint(err, errors.FormatOptions{Details: true, Mode: errors.FormatLogfmt}))
+}
+
+func TestParseLogfmt(t *testing.T) {
+	t.Parallel()
+
+	err, errE := errors.ParseLogfmt("")
+	assert.NoError(t, errE)
+	assert.NoError(t, err)
+
+	err, errE = errors.ParseLogfmt(`error="unterminated`)
+	assert.EqualError(t, errE, `unterminated value for key "error"`)
+	assert.NoError(t, err)
+
+	err, errE = errors.ParseLogfmt(`=value`)
+	assert.EqualError(t, errE, `missing key at position 0`)
+	assert.NoError(t, err)
+
+	err, errE = errors.ParseLogfmt(`error`)
+	assert.EqualError(t, errE, `missing value for key "error"`)
+	assert.NoError(t, err)
+
+	err, errE = errors.ParseLogfmt(`"a\q"=1`)
+	assert.EqualError(t, errE, `invalid key "a\q"`)
+	assert.NoError(t, err)
+
+	err, errE = errors.ParseLogfmt(`"a=1`)
+	assert.EqualError(t, errE, `unterminated key at position 0`)
+	assert.NoError(t, err)
+
+	// Reserved keys are not formatted as details.
+	err = errors.WithDetails(errors.Base("error"), "fingerprint", "abc", "boundaries", "x", "stack", "y", "key", "value")
+	assert.Equal(t, "error=error key=value\n", fmt.Sprintf("%#.5v", err))
+
+	// Values which are not valid stack traces are kept as details.
+	err, errE = errors.ParseLogfmt(`error=error stack=invalid` + "\n")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, "error\nstack=invalid\n", fmt.Sprintf("%#.1v", err))
+}
@@ -0,0 +1,189 @@
+package errors
+
+import (
+	"reflect"
+	"regexp"
+)
+
+// Matcher reports whether node matches. ancestors are nodes on the path
+// from the root of the tree of errors to node, root first, excluding node.
+//
+// Matchers which inspect the error itself (IsBase, Type, HasDetail) consider
+// all errors at the node's level: errors obtained by unwrapping node's error
+// until a cause or joined errors are found, the same as Cause and Unjoin do.
+type Matcher func(node Node, ancestors []Node) bool
+
+// Match walks the tree of errors rooted at err in the same order as Walk
+// and returns the first node matching m. It returns false if no node matches.
+//
+// Matchers can be composed, e.g., to find a NotFound base error with detail
+// "resource" equal to "user" somewhere under a cause of type *url.Error:
+//
+//	errors.Match(err, errors.EveryOf(
+//		errors.IsBase(NotFound),
+//		errors.HasDetail("resource", "user"),
+//		errors.CauseOf(errors.Type[*url.Error]()),
+//	))
+func Match(err error, m Matcher) (Node, bool) {
+	if err == nil {
+		return Node{}, false //nolint:exhaustruct
+	}
+	return matchTree(Node{Err: err, Depth: 0, Path: []int{}, Relation: RelationRoot}, []Node{}, m)
+}
+
+// matchTree walks the tree of errors rooted at root and returns the first node matching m.
+func matchTree(root Node, ancestors []Node, m Matcher) (Node, bool) {
+	var result Node
+	found := false
+	stack := append([]Node(nil), ancestors...)
+	errs := make([]error, len(ancestors))
+	for i, a := range ancestors {
+		errs[i] = a.Err
+	}
+	walk(root, errs, func(node Node) WalkAction {
+		stack = stack[:node.Depth]
+		if m(node, stack) {
+			result = node
+			found = true
+			return WalkStop
+		}
+		stack = append(stack, node)
+		return WalkContinue
+	})
+	return result, found
+}
+
+// matchLevel returns true if fn returns true for any error at err's level.
+func matchLevel(err error, fn func(err error) bool) bool {
+	var cycle unwrapCycle
+	for err != nil && !cycle.repeated(err) {
+		if fn(err) {
+			return true
+		}
+		c, ok := err.(causer)
+		if ok && c.Cause() != nil {
+			return false
+		}
+		e, ok := err.(unwrapperJoined)
+		if ok && len(e.Unwrap()) > 0 {
+			return false
+		}
+		err = Unwrap(err)
+	}
+	return false
+}
+
+// IsBase returns a matcher which matches a node if base is at the node's
+// level, i.e., it is the same error as base or has an Is(error) bool
+// method which returns true for base.
+func IsBase(base error) Matcher {
+	return func(node Node, _ []Node) bool {
+		return matchLevel(node.Err, func(err error) bool {
+			if isSameError(err, base) {
+				return true
+			}
+			x, ok := err.(interface{ Is(error) bool }) //nolint:inamedparam
+			return ok && x.Is(base)
+		})
+	}
+}
+
+// Type returns a matcher which matches a node if an error
+// of type T is at the node's level.
+func Type[T error]() Matcher {
+	return func(node Node, _ []Node) bool {
+		return matchLevel(node.Err, func(err error) bool {
+			_, ok := err.(T) //nolint:errorlint
+			return ok
+		})
+	}
+}
+
+// HasDetail returns a matcher which matches a node if details of the node's
+// level (see AllDetails) contain key with value deeply equal to value.
+func HasDetail(key string, value interface{}) Matcher {
+	return func(node Node, _ []Node) bool {
+		v, ok := AllDetails(node.Err)[key]
+		return ok && reflect.DeepEqual(v, value)
+	}
+}
+
+// MessageRegexp returns a matcher which matches a node
+// if the node's error message matches re.
+func MessageRegexp(re *regexp.Regexp) Matcher {
+	return func(node Node, _ []Node) bool {
+		return re.MatchString(node.Err.Error())
+	}
+}
+
+// CauseOf returns a matcher which matches a node if it is somewhere
+// under (but not at) a cause (see Cause) which matches m.
+func CauseOf(m Matcher) Matcher {
+	return func(_ Node, ancestors []Node) bool {
+		for i := len(ancestors) - 1; i >= 0; i-- {
+			if ancestors[i].Relation == RelationCause && m(ancestors[i], ancestors[:i]) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// JoinedContains returns a matcher which matches a node if any of joined errors
+// of the node's level (see Unjoin), or any error under them, matches m.
+func JoinedContains(m Matcher) Matcher {
+	return func(node Node, ancestors []Node) bool {
+		ancestors = append(ancestors[:len(ancestors):len(ancestors)], node)
+		errs := []error{node.Err}
+		for {
+			children := children(node.Err)
+			e, ok := node.Err.(unwrapperJoined)
+			if ok && len(e.Unwrap()) > 0 {
+				for i, c := range children {
+					if _, ok := matchTree(childNode(node, i, c), ancestors, m); ok {
+						return true
+					}
+				}
+				return false
+			}
+			if len(children) != 1 || children[0].relation != RelationUnwrap || isAncestor(children[0].err, errs) {
+				return false
+			}
+			node = childNode(node, 0, children[0])
+			ancestors = append(ancestors, node)
+			errs = append(errs, node.Err)
+		}
+	}
+}
+
+// AnyOf returns a matcher which matches a node if any of matchers matches it.
+func AnyOf(matchers ...Matcher) Matcher {
+	return func(node Node, ancestors []Node) bool {
+		for _, m := range matchers {
+			if m(node, ancestors) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// EveryOf returns a matcher which matches a node if all of matchers match it.
+// It is not named AllOf because AllOf is the iterator over errors of a type.
+func EveryOf(matchers ...Matcher) Matcher {
+	return func(node Node, ancestors []Node) bool {
+		for _, m := range matchers {
+			if !m(node, ancestors) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Not returns a matcher which matches a node if m does not match it.
+func Not(m Matcher) Matcher {
+	return func(node Node, ancestors []Node) bool {
+		return !m(node, ancestors)
+	}
+}
@@ -0,0 +1,67 @@
+package errors_test
+
+import (
+	"io"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+var errNotFound = errors.Base("not found")
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	notFound := errors.WithDetails(errNotFound, "resource", "user")
+	urlErr := &url.Error{Op: "Get", URL: "https://example.com", Err: notFound}
+	err := errors.Join(
+		errors.WithDetails(errNotFound, "resource", "user"),
+		errors.Wrap(urlErr, "fetch"),
+	)
+
+	pattern := errors.EveryOf(
+		errors.IsBase(errNotFound),
+		errors.HasDetail("resource", "user"),
+		errors.CauseOf(errors.Type[*url.Error]()),
+	)
+
+	tests := []struct {
+		Name    string
+		Err     error
+		Matcher errors.Matcher
+		Path    []int
+	}{
+		{"nil", nil, errors.IsBase(errNotFound), nil},
+		{"isBase", err, errors.IsBase(errNotFound), []int{0}},
+		{"isBaseNone", err, errors.IsBase(io.EOF), nil},
+		{"type", err, errors.Type[*url.Error](), []int{1, 0}},
+		{"hasDetail", err, errors.HasDetail("resource", "user"), []int{0}},
+		{"hasDetailValue", err, errors.HasDetail("resource", "group"), nil},
+		{"messageRegexp", err, errors.MessageRegexp(regexp.MustCompile(`^Get `)), []int{1, 0}},
+		{"causeOf", err, pattern, []int{1, 0, 0}},
+		{"causeOfNone", errors.Join(notFound), pattern, nil},
+		{"joinedContains", errors.WithStack(err), errors.JoinedContains(errors.Type[*url.Error]()), []int{}},
+		{"joinedContainsNone", errors.Wrap(urlErr, "fetch"), errors.JoinedContains(errors.Type[*url.Error]()), nil},
+		{"anyOf", err, errors.AnyOf(errors.IsBase(io.EOF), errors.Type[*url.Error]()), []int{1, 0}},
+		{"not", err, errors.Not(errors.MessageRegexp(regexp.MustCompile(`\n`))), []int{0}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+
+			node, ok := errors.Match(tt.Err, tt.Matcher)
+			if tt.Path == nil {
+				assert.False(t, ok)
+			} else if assert.True(t, ok) {
+				assert.Equal(t, tt.Path, node.Path)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"runtime"
 )
 
 type placeholderStackTracer interface {
@@ -31,16 +32,38 @@ type placeholderStackTracer interface {
 // of wrapping are combined into one JSON object. Nested objects happen
 // only for errors implementing causer or unwrapper interface returning
 // multiple errors.
+//
+// Back-references to errors which made a cycle when marshaled (JSON objects
+// with only the "$ref" field) are unmarshaled into placeholder errors which
+// are formatted as back-references and marshaled back into the same JSON.
 func UnmarshalJSON(data []byte) (error, E) { //nolint:revive,staticcheck
+	return unmarshalJSONDepth(data, 0)
+}
+
+// unmarshalJSONDepth unmarshals JSON error at depth. Nested causes and joined
+// errors deeper than maxUnmarshalDepth are not unmarshaled but kept as details.
+func unmarshalJSONDepth(data []byte, depth int) (error, E) { //nolint:revive,staticcheck
 	if bytes.Equal(data, []byte("null")) {
 		return nil, nil //nolint:nilnil
 	}
+	if depth > maxUnmarshalDepth {
+		return nil, Errorf("maximum depth of %d exceeded", maxUnmarshalDepth)
+	}
 	var payload map[string]json.RawMessage
 	err := json.Unmarshal(data, &payload)
 	if err != nil {
 		return nil, WithStack(err)
 	}
 
+	refData, ok := payload[jsonRef]
+	if ok && len(payload) == 1 {
+		var ref string
+		err := json.Unmarshal(refData, &ref)
+		if err == nil {
+			return &placeholderRefError{ref: ref}, nil
+		}
+	}
+
 	var errE E
 	var msg string
 	var s placeholderStack
@@ -70,10 +93,92 @@ func UnmarshalJSON(data []byte) (error, E) { //nolint:revive,staticcheck
 		}
 	}
 
+	var boundaries []placeholderStack
+	boundariesData, ok := payload["boundaries"]
+	delete(payload, "boundaries")
+	if ok {
+		err := json.Unmarshal(boundariesData, &boundaries)
+		if err != nil {
+			// "boundaries" field is not a list of stack traces, treat it as a detail.
+			payload["boundaries"] = boundariesData
+			boundaries = nil
+		} else if len(boundaries) == 0 {
+			boundaries = nil
+		}
+	}
+
+	var returnTrace placeholderStack
+	returnTraceData, ok := payload["return_trace"]
+	delete(payload, "return_trace")
+	if ok {
+		err := json.Unmarshal(returnTraceData, &returnTrace)
+		if err != nil {
+			// "return_trace" field is not a stack trace, treat it as a detail.
+			payload["return_trace"] = returnTraceData
+			returnTrace = nil
+		} else if len(returnTrace) == 0 {
+			returnTrace = nil
+		}
+	}
+
+	var fingerprint string
+	fingerprintData, ok := payload["fingerprint"]
+	delete(payload, "fingerprint")
+	if ok {
+		err := json.Unmarshal(fingerprintData, &fingerprint)
+		if err != nil {
+			// "fingerprint" field is not a string, treat it as a detail.
+			payload["fingerprint"] = fingerprintData
+		}
+	}
+
+	var omitted omittedParts
+	errorsOmittedData, ok := payload[jsonErrorsOmitted]
+	delete(payload, jsonErrorsOmitted)
+	if ok {
+		err := json.Unmarshal(errorsOmittedData, &omitted.errors)
+		if err != nil {
+			// "errors_omitted" field is not an integer, treat it as a detail.
+			payload[jsonErrorsOmitted] = errorsOmittedData
+			omitted.errors = 0
+		}
+	}
+	detailsOmittedData, ok := payload[jsonDetailsOmitted]
+	delete(payload, jsonDetailsOmitted)
+	if ok {
+		err := json.Unmarshal(detailsOmittedData, &omitted.details)
+		if err != nil {
+			// "details_omitted" field is not an integer, treat it as a detail.
+			payload[jsonDetailsOmitted] = detailsOmittedData
+			omitted.details = 0
+		}
+	}
+	depthLimitReachedData, ok := payload[jsonDepthLimitReached]
+	delete(payload, jsonDepthLimitReached)
+	if ok {
+		err := json.Unmarshal(depthLimitReachedData, &omitted.depthLimit)
+		if err != nil {
+			// "depth_limit_reached" field is not a boolean, treat it as a detail.
+			payload[jsonDepthLimitReached] = depthLimitReachedData
+			omitted.depthLimit = false
+		}
+	}
+	returnTraceOmittedData, ok := payload[jsonReturnTraceOmitted]
+	delete(payload, jsonReturnTraceOmitted)
+	if ok {
+		err := json.Unmarshal(returnTraceOmittedData, &omitted.returnTrace)
+		if err != nil || returnTrace == nil {
+			// "return_trace_omitted" field is not an integer or there is no
+			// return trace, treat it as a detail.
+			payload[jsonReturnTraceOmitted] = returnTraceOmittedData
+			omitted.returnTrace = 0
+		}
+	}
+
 	causeData, ok := payload["cause"]
 	delete(payload, "cause")
 	if ok {
-		cause, errE = UnmarshalJSON(causeData)
+		cause, errE = unmarshalJSONDepth(causeData, depth+1)
 		if errE != nil {
 			// "cause" field is not an error, treat it as a detail.
 			payload["cause"] = causeData
@@ -92,7 +197,7 @@ func UnmarshalJSON(data []byte) (error, E) { //nolint:revive,staticcheck
 			errs = nil
 		} else {
 			for _, d := range errorsSliceData {
-				e, errE := UnmarshalJSON(d)
+				e, errE := unmarshalJSONDepth(d, depth+1)
 				if errE != nil {
 					// "errors" field is not a slice of errors, treat it as a detail.
 					payload["errors"] = errorsData
@@ -129,31 +234,47 @@ func UnmarshalJSON(data []byte) (error, E) { //nolint:revive,staticcheck
 
 	if cause != nil && len(errs) > 0 {
 		return &placeholderJoinedCauseError{
-			msg:     msg,
-			stack:   s,
-			details: details,
-			cause:   cause,
-			errs:    errs,
+			msg:         msg,
+			stack:       s,
+			details:     details,
+			cause:       cause,
+			errs:        errs,
+			boundaries:  boundaries,
+			returnTrace: returnTrace,
+			fingerprint: fingerprint,
+			omitted:     omitted,
 		}, nil
 	} else if cause != nil {
 		return &placeholderCauseError{
-			msg:     msg,
-			stack:   s,
-			details: details,
-			cause:   cause,
+			msg:         msg,
+			stack:       s,
+			details:     details,
+			cause:       cause,
+			boundaries:  boundaries,
+			returnTrace: returnTrace,
+			fingerprint: fingerprint,
+			omitted:     omitted,
 		}, nil
 	} else if len(errs) > 0 {
 		return &placeholderJoinedError{
-			msg:     msg,
-			stack:   s,
-			details: details,
-			errs:    errs,
+			msg:         msg,
+			stack:       s,
+			details:     details,
+			errs:        errs,
+			boundaries:  boundaries,
+			returnTrace: returnTrace,
+			fingerprint: fingerprint,
+			omitted:     omitted,
 		}, nil
 	}
 	return &placeholderError{
-		msg:     msg,
-		stack:   s,
-		details: details,
+		msg:         msg,
+		stack:       s,
+		details:     details,
+		boundaries:  boundaries,
+		returnTrace: returnTrace,
+		fingerprint: fingerprint,
+		omitted:     omitted,
 	}, nil
 }
 
@@ -176,12 +297,35 @@ func (s placeholderStack) Format(st fmt.State, verb rune) {
 	}
 }
 
+func (s placeholderStack) MarshalJSON() ([]byte, error) {
+	return marshalWithoutEscapeHTML([]placeholderFrame(s))
+}
+
+// frames returns s as runtime frames.
+func (s placeholderStack) frames() []runtime.Frame {
+	result := make([]runtime.Frame, 0, len(s))
+	for _, f := range s {
+		result = append(result, runtime.Frame{ //nolint:exhaustruct
+			Function: f.Name,
+			File:     f.File,
+			Line:     f.Line,
+		})
+	}
+	return result
+}
+
 type placeholderError struct {
-	msg     string
-	stack   placeholderStack
-	details map[string]interface{}
+	msg         string
+	stack       placeholderStack
+	details     map[string]interface{}
+	boundaries  []placeholderStack
+	returnTrace placeholderStack
+	fingerprint string
+	omitted     omittedParts
 }
 
+func (e *placeholderError) ownError() {}
+
 func (e *placeholderError) Error() string {
 	if isCalledFromRuntimePanic() {
 		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
@@ -193,8 +337,8 @@ func (e *placeholderError) Format(s fmt.State, verb rune) {
 	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
 }
 
-func (e placeholderError) MarshalJSON() ([]byte, error) {
-	return marshalJSONError(&e)
+func (e *placeholderError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
 }
 
 func (e *placeholderError) StackTrace() placeholderStack {
@@ -205,13 +349,35 @@ func (e *placeholderError) Details() map[string]interface{} {
 	return e.details
 }
 
+func (e *placeholderError) BoundariesStackTraces() []placeholderStack {
+	return e.boundaries
+}
+
+func (e *placeholderError) ReturnTrace() placeholderStack {
+	return e.returnTrace
+}
+
+func (e *placeholderError) Fingerprint() string {
+	return e.fingerprint
+}
+
+func (e *placeholderError) OmittedParts() omittedParts {
+	return e.omitted
+}
+
 type placeholderCauseError struct {
-	msg     string
-	stack   placeholderStack
-	details map[string]interface{}
-	cause   error
+	msg         string
+	stack       placeholderStack
+	details     map[string]interface{}
+	cause       error
+	boundaries  []placeholderStack
+	returnTrace placeholderStack
+	fingerprint string
+	omitted     omittedParts
 }
 
+func (e *placeholderCauseError) ownError() {}
+
 func (e *placeholderCauseError) Error() string {
 	if isCalledFromRuntimePanic() {
 		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
@@ -223,8 +389,8 @@ func (e *placeholderCauseError) Format(s fmt.State, verb rune) {
 	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
 }
 
-func (e placeholderCauseError) MarshalJSON() ([]byte, error) {
-	return marshalJSONError(&e)
+func (e *placeholderCauseError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
 }
 
 func (e *placeholderCauseError) StackTrace() placeholderStack {
@@ -235,6 +401,22 @@ func (e *placeholderCauseError) Details() map[string]interface{} {
 	return e.details
 }
 
+func (e *placeholderCauseError) BoundariesStackTraces() []placeholderStack {
+	return e.boundaries
+}
+
+func (e *placeholderCauseError) ReturnTrace() placeholderStack {
+	return e.returnTrace
+}
+
+func (e *placeholderCauseError) Fingerprint() string {
+	return e.fingerprint
+}
+
+func (e *placeholderCauseError) OmittedParts() omittedParts {
+	return e.omitted
+}
+
 func (e *placeholderCauseError) Unwrap() error {
 	return e.cause
 }
@@ -244,12 +426,18 @@ func (e *placeholderCauseError) Cause() error {
 }
 
 type placeholderJoinedError struct {
-	msg     string
-	stack   placeholderStack
-	details map[string]interface{}
-	errs    []error
+	msg         string
+	stack       placeholderStack
+	details     map[string]interface{}
+	errs        []error
+	boundaries  []placeholderStack
+	returnTrace placeholderStack
+	fingerprint string
+	omitted     omittedParts
 }
 
+func (e *placeholderJoinedError) ownError() {}
+
 func (e *placeholderJoinedError) Error() string {
 	if isCalledFromRuntimePanic() {
 		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
@@ -261,8 +449,8 @@ func (e *placeholderJoinedError) Format(s fmt.State, verb rune) {
 	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
 }
 
-func (e placeholderJoinedError) MarshalJSON() ([]byte, error) {
-	return marshalJSONError(&e)
+func (e *placeholderJoinedError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
 }
 
 func (e *placeholderJoinedError) StackTrace() placeholderStack {
@@ -273,18 +461,40 @@ func (e *placeholderJoinedError) Details() map[string]interface{} {
 	return e.details
 }
 
+func (e *placeholderJoinedError) BoundariesStackTraces() []placeholderStack {
+	return e.boundaries
+}
+
+func (e *placeholderJoinedError) ReturnTrace() placeholderStack {
+	return e.returnTrace
+}
+
+func (e *placeholderJoinedError) Fingerprint() string {
+	return e.fingerprint
+}
+
+func (e *placeholderJoinedError) OmittedParts() omittedParts {
+	return e.omitted
+}
+
 func (e *placeholderJoinedError) Unwrap() []error {
 	return e.errs
 }
 
 type placeholderJoinedCauseError struct {
-	msg     string
-	stack   placeholderStack
-	details map[string]interface{}
-	cause   error
-	errs    []error
+	msg         string
+	stack       placeholderStack
+	details     map[string]interface{}
+	cause       error
+	errs        []error
+	boundaries  []placeholderStack
+	returnTrace placeholderStack
+	fingerprint string
+	omitted     omittedParts
 }
 
+func (e *placeholderJoinedCauseError) ownError() {}
+
 func (e *placeholderJoinedCauseError) Error() string {
 	if isCalledFromRuntimePanic() {
 		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
@@ -296,8 +506,8 @@ func (e *placeholderJoinedCauseError) Format(s fmt.State, verb rune) {
 	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
 }
 
-func (e placeholderJoinedCauseError) MarshalJSON() ([]byte, error) {
-	return marshalJSONError(&e)
+func (e *placeholderJoinedCauseError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
 }
 
 func (e *placeholderJoinedCauseError) StackTrace() placeholderStack {
@@ -308,6 +518,22 @@ func (e *placeholderJoinedCauseError) Details() map[string]interface{} {
 	return e.details
 }
 
+func (e *placeholderJoinedCauseError) BoundariesStackTraces() []placeholderStack {
+	return e.boundaries
+}
+
+func (e *placeholderJoinedCauseError) ReturnTrace() placeholderStack {
+	return e.returnTrace
+}
+
+func (e *placeholderJoinedCauseError) Fingerprint() string {
+	return e.fingerprint
+}
+
+func (e *placeholderJoinedCauseError) OmittedParts() omittedParts {
+	return e.omitted
+}
+
 func (e *placeholderJoinedCauseError) Unwrap() []error {
 	return e.errs
 }
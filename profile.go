@@ -0,0 +1,82 @@
+package errors
+
+import (
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// CreatedProfileName is the name of the runtime/pprof profile with
+	// all errors created by this package since EnableProfiles was called.
+	CreatedProfileName = "gitlab.com/tozd/go/errors.created"
+
+	// LiveProfileName is the name of the runtime/pprof profile with
+	// errors created by this package since EnableProfiles was called
+	// which have not yet been garbage collected.
+	LiveProfileName = "gitlab.com/tozd/go/errors.live"
+)
+
+var (
+	// profilesEnabled is the number of calls to EnableProfiles
+	// for which the returned function has not yet been called.
+	profilesEnabled int32
+	profilesOnce    sync.Once
+	createdProfile  *pprof.Profile
+	liveProfile     *pprof.Profile
+)
+
+// EnableProfiles registers custom runtime/pprof profiles named by
+// CreatedProfileName and LiveProfileName and starts recording a sample
+// for every error created by New, Errorf, WithStack, WithDetails, Wrap,
+// and other functions of this package which return a new error.
+// It returns a function which stops recording. It is safe to call
+// EnableProfiles multiple times: recording stops once all functions
+// returned by calls to EnableProfiles are called.
+//
+// Each sample contains the stack trace at the point the function creating
+// the error was called. Profiles can be obtained using pprof.Lookup or,
+// if net/http/pprof is imported, at /debug/pprof/<name>, and
+// inspected with "go tool pprof" to find call sites producing the most errors.
+//
+// The live profile contains only errors which have not yet been garbage
+// collected. This is tracked using a finalizer set on each error, so errors
+// are released one garbage collection cycle later than otherwise.
+// The created profile contains all errors created and it keeps growing
+// for the lifetime of the program, so enable profiles only when diagnosing.
+// Samples recorded before recording stops stay in profiles (samples of
+// errors in the live profile are still removed when errors are garbage
+// collected), so profiles can be inspected after recording stops.
+func EnableProfiles() func() {
+	profilesOnce.Do(func() {
+		createdProfile = pprof.NewProfile(CreatedProfileName)
+		liveProfile = pprof.NewProfile(LiveProfileName)
+	})
+	atomic.AddInt32(&profilesEnabled, 1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			atomic.AddInt32(&profilesEnabled, -1)
+		})
+	}
+}
+
+// recordProfiles records err in profiles.
+// extraSkip is the number of additional frames to skip between the function
+// creating the error and the exported function called by the user.
+func recordProfiles(err E, extraSkip int) {
+	// Skip pprof.Profile.Add, recordProfiles, record, and the function which created the error.
+	skip := 4 + extraSkip //nolint:mnd
+
+	createdProfile.Add(new(byte), skip)
+
+	// We cannot use err itself as the key because then the profile would
+	// reference it and it would never be garbage collected.
+	key := new(byte)
+	liveProfile.Add(key, skip)
+	runtime.SetFinalizer(err, func(interface{}) {
+		liveProfile.Remove(key)
+	})
+}
@@ -0,0 +1,83 @@
+package errors_test
+
+import (
+	"bytes"
+	"runtime"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+//go:noinline
+func profileNew() errors.E {
+	return errors.New("error")
+}
+
+//go:noinline
+func profileWithStack() errors.E {
+	return errors.WithStack(errors.Base("error"))
+}
+
+//go:noinline
+func profileWithMessage() errors.E {
+	return errors.WithMessage(errors.Base("error"), "prefix")
+}
+
+func TestProfiles(t *testing.T) { //nolint:paralleltest
+	disable1 := errors.EnableProfiles()
+	t.Cleanup(disable1)
+	disable2 := errors.EnableProfiles()
+	t.Cleanup(disable2)
+
+	createdProfile := pprof.Lookup(errors.CreatedProfileName)
+	require.NotNil(t, createdProfile)
+	liveProfile := pprof.Lookup(errors.LiveProfileName)
+	require.NotNil(t, liveProfile)
+
+	created := createdProfile.Count()
+
+	errs := []errors.E{}
+	for i := 0; i < 10; i++ {
+		errs = append(errs, profileNew(), profileWithStack(), profileWithMessage())
+	}
+	// Existing error with a stack trace is returned as-is and is not recorded again.
+	assert.Equal(t, errs[0], errors.WithStack(errs[0]))
+
+	assert.GreaterOrEqual(t, createdProfile.Count()-created, 30)
+	assert.GreaterOrEqual(t, liveProfile.Count(), 30)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, createdProfile.WriteTo(buf, 1))
+	// The first frame of every sample is the caller of the function which created the error.
+	for _, function := range []string{"profileNew", "profileWithStack", "profileWithMessage"} {
+		assert.Regexp(t, `(?m)^\d+ @ .*\n#\t0x[0-9a-f]+\tgitlab\.com/tozd/go/errors_test\.`+function+`\+`, buf.String())
+	}
+	assert.NotContains(t, buf.String(), "gitlab.com/tozd/go/errors.record")
+	assert.NotContains(t, buf.String(), "gitlab.com/tozd/go/errors.New")
+
+	live := liveProfile.Count()
+	runtime.KeepAlive(errs)
+	errs = nil //nolint:ineffassign,wastedassign
+	for i := 0; i < 10 && liveProfile.Count() > live-30; i++ {
+		// Finalizers run after the first garbage collection and release errors for the next one.
+		runtime.GC()
+	}
+	assert.LessOrEqual(t, liveProfile.Count(), live-30)
+	assert.GreaterOrEqual(t, createdProfile.Count()-created, 30)
+
+	// Recording stops only after all returned functions are called.
+	disable1()
+	disable1()
+	created = createdProfile.Count()
+	_ = profileNew()
+	assert.Equal(t, created+1, createdProfile.Count())
+
+	disable2()
+	created = createdProfile.Count()
+	_ = profileNew()
+	assert.Equal(t, created, createdProfile.Count())
+}
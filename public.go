@@ -0,0 +1,149 @@
+package errors
+
+import "fmt"
+
+type publicMessager interface {
+	PublicMessage() string
+}
+
+// WithPublicMessage annotates err with a message which is safe to show
+// to end users.
+// If err does not have a stack trace, stack strace is recorded as well.
+// If err is nil, WithPublicMessage returns nil.
+//
+// The public message does not change the error message of err and it is
+// not included in formatting nor JSON marshaling of err, unless public
+// mode is requested through Formatter. Use PublicMessage to obtain it.
+//
+// Use WithPublicMessage when the error message, details and stack trace
+// are meant for developers, but you also want to provide a message to
+// end users.
+func WithPublicMessage(err error, message string) E {
+	if err == nil {
+		return nil
+	}
+
+	st := getExistingStackTrace(err)
+	newStack := len(st) == 0
+	if newStack {
+		st = callers(0)
+	}
+
+	return record(&publicError{
+		annotation: newAnnotation(err, st),
+		publicMsg:  message,
+	}, EventWithPublicMessage, newStack, 0)
+}
+
+// publicError wraps another error and has its own
+// stack and public message, but does not have its own msg.
+type publicError struct {
+	annotation
+	publicMsg string
+}
+
+func (e *publicError) Error() string {
+	if isCalledFromRuntimePanic() {
+		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
+	}
+	return e.err.Error()
+}
+
+func (e *publicError) Format(s fmt.State, verb rune) {
+	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
+}
+
+func (e *publicError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
+}
+
+func (e *publicError) PublicMessage() string {
+	return e.publicMsg
+}
+
+// PublicMessage returns a message for err which is safe to show to end users.
+//
+// It returns the result of calling the PublicMessage method on the closest
+// error in err's tree which has that method returning a non-empty string.
+// The tree is traversed breadth-first, so public messages closer to err
+// take precedence.
+//
+// If there is no such error, PublicMessage falls back to the error message
+// of the closest error in err's tree made with BaseWrap or BaseWrapf, and
+// then to the result of calling the ErrorCode method on the closest error
+// which has that method returning a non-empty string. This assumes that
+// messages of such base errors and error codes do not contain sensitive
+// information. Messages of other errors are never used because they might
+// (e.g., errors made with fmt.Errorf often include arguments). This includes
+// errors made with Base and Basef because they are standard errors and cannot
+// be told apart from other standard errors. Use WithPublicMessage or Catalog
+// to provide public messages for them.
+//
+// If there is none of those either, or if err is nil, PublicMessage
+// returns an empty string.
+func PublicMessage(err error) string {
+	e := closest(err, func(e error) bool {
+		p, ok := e.(publicMessager)
+		return ok && p.PublicMessage() != ""
+	})
+	if e != nil {
+		return e.(publicMessager).PublicMessage() //nolint:forcetypeassert,errcheck,errorlint
+	}
+
+	e = closest(err, isBaseError)
+	if e != nil {
+		return e.Error()
+	}
+
+	e = closest(err, func(e error) bool {
+		c, ok := e.(errorCoder)
+		return ok && c.ErrorCode() != ""
+	})
+	if e != nil {
+		return e.(errorCoder).ErrorCode() //nolint:forcetypeassert,errcheck,errorlint
+	}
+
+	return ""
+}
+
+// isBaseError returns true if err is made by BaseWrap or BaseWrapf.
+func isBaseError(err error) bool {
+	_, ok := err.(*base) //nolint:errorlint
+	return ok
+}
+
+// closest traverses err's tree breadth-first and returns the first error
+// for which fn returns true. It returns nil if there is no such error.
+func closest(err error, fn func(error) bool) error {
+	if err == nil {
+		return nil
+	}
+	queue := []error{err}
+	// We track visited errors to not loop forever on cycles.
+	visited := []error{}
+	for len(queue) > 0 {
+		e := queue[0]
+		queue = queue[1:]
+		if isAncestor(e, visited) {
+			continue
+		}
+		visited = append(visited, e)
+		if fn(e) {
+			return e
+		}
+		switch u := e.(type) { //nolint:errorlint
+		case unwrapperJoined:
+			for _, er := range u.Unwrap() {
+				if er != nil {
+					queue = append(queue, er)
+				}
+			}
+		case unwrapper:
+			er := u.Unwrap()
+			if er != nil {
+				queue = append(queue, er)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package errors
+
+import (
+	"fmt"
+)
+
+// panicMessage returns the message describing a panic with value r
+// in the method of an error, in the same way as fmt does.
+func panicMessage(method string, r interface{}) string {
+	return fmt.Sprintf("%%!v(PANIC=%s method: %v)", method, r)
+}
+
+// recoverPanic recovers a panic in a method of a (foreign) error, calling
+// fn with a message describing the panic. Method points to the name of
+// the method being called at the time of the panic.
+//
+// It has to be deferred directly for recover to work.
+func recoverPanic(method *string, fn func(msg string)) {
+	r := recover()
+	if r != nil {
+		fn(panicMessage(*method, r))
+	}
+}
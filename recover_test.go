@@ -0,0 +1,154 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+type nilReceiverError struct {
+	msg string
+}
+
+func (e *nilReceiverError) Error() string {
+	return e.msg
+}
+
+type panicDetailsError struct{}
+
+func (e panicDetailsError) Error() string {
+	return "details"
+}
+
+func (e panicDetailsError) Details() map[string]interface{} {
+	panic("no details")
+}
+
+type panicStackError struct{}
+
+func (e panicStackError) Error() string {
+	return "stack"
+}
+
+func (e panicStackError) StackTrace() []uintptr {
+	panic("no stack")
+}
+
+type panicMarshalError struct{}
+
+func (e panicMarshalError) Error() string {
+	return "marshal"
+}
+
+func (e panicMarshalError) MarshalJSON() ([]byte, error) {
+	panic("no JSON")
+}
+
+type panicFormatError struct{}
+
+func (e panicFormatError) Error() string {
+	return "format"
+}
+
+func (e panicFormatError) Format(fmt.State, rune) {
+	panic("no format")
+}
+
+type detailValueError struct{}
+
+func (e detailValueError) Error() string {
+	return "error"
+}
+
+func (e detailValueError) Details() map[string]interface{} {
+	return map[string]interface{}{"value": panicMarshalError{}}
+}
+
+type lazyJoinedError []error
+
+func (e lazyJoinedError) Error() string {
+	return "joined"
+}
+
+func (e lazyJoinedError) Unwrap() []error {
+	return e
+}
+
+func TestPanicSafe(t *testing.T) {
+	t.Parallel()
+
+	var nilErr *nilReceiverError
+
+	tests := []struct {
+		Name      string
+		Err       error
+		Formatted string
+		JSON      string
+	}{
+		{
+			"Error",
+			nilErr,
+			"%!v(PANIC=Error method: runtime error: invalid memory address or nil pointer dereference)\n",
+			`{"error":"%!v(PANIC=Error method: runtime error: invalid memory address or nil pointer dereference)"}`,
+		},
+		{
+			"Details",
+			panicDetailsError{},
+			"details\n%!v(PANIC=Details method: no details)\n",
+			`{"error":"%!v(PANIC=Details method: no details)"}`,
+		},
+		{
+			"StackTrace",
+			panicStackError{},
+			"stack\n%!v(PANIC=StackTrace method: no stack)\n",
+			`{"error":"%!v(PANIC=StackTrace method: no stack)"}`,
+		},
+		{
+			"MarshalJSON",
+			panicMarshalError{},
+			"marshal\n",
+			`{"error":"%!v(PANIC=MarshalJSON method: no JSON)"}`,
+		},
+		{
+			"Format",
+			panicFormatError{},
+			"%!v(PANIC=Format method: no format)\n",
+			`{"error":"format"}`,
+		},
+		{
+			"detail",
+			detailValueError{},
+			"error\nvalue=\"%!v(PANIC=MarshalJSON method: no JSON)\"\n",
+			`{"error":"%!v(PANIC=MarshalJSON method: no JSON)"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.Formatted, fmt.Sprintf("%#+.1v", errors.Formatter{Error: tt.Err}))
+			assert.Equal(t, tt.Formatted, fmt.Sprintf("%#+.4v", errors.Formatter{Error: tt.Err}))
+
+			data, e := json.Marshal(errors.Formatter{Error: tt.Err})
+			require.NoError(t, e)
+			assert.JSONEq(t, tt.JSON, string(data))
+
+			// Errors are guarded also when they are joined or wrapped.
+			joined := fmt.Sprintf("% #+.1v", errors.Formatter{Error: lazyJoinedError{errors.Base("joined"), tt.Err}})
+			assert.Contains(t, joined, "\t"+strings.ReplaceAll(strings.TrimSuffix(tt.Formatted, "\n"), "\n", "\n\t")+"\n")
+
+			data, e = json.Marshal(errors.Formatter{Error: errors.WrapWith(tt.Err, errors.Base("wrapped"))})
+			require.NoError(t, e)
+			assert.Contains(t, string(data), `"error":"wrapped"`)
+		})
+	}
+}
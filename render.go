@@ -0,0 +1,166 @@
+package errors
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Renderer renders the tree of errors as a graph.
+//
+// Each error in the tree (see Walk) is rendered as a node showing its message,
+// its Go type, and its details. Edges between nodes are labeled with
+// the relation of the error to its parent (see Relation).
+type Renderer struct {
+	// Number of top stack frames to annotate nodes with.
+	// Frames are shown only for errors with a stack trace different
+	// from the stack trace of their parent.
+	Frames int `exhaustruct:"optional"`
+}
+
+// RenderDOT renders the tree of errors rooted at err
+// into w in Graphviz DOT language, without stack frames.
+//
+// See Renderer.DOT for more information.
+func RenderDOT(w io.Writer, err error) E {
+	return Renderer{}.DOT(w, err)
+}
+
+// RenderMermaid renders the tree of errors rooted at err
+// into w as a Mermaid flowchart, without stack frames.
+//
+// See Renderer.Mermaid for more information.
+func RenderMermaid(w io.Writer, err error) E {
+	return Renderer{}.Mermaid(w, err)
+}
+
+type renderNode struct {
+	id       string
+	parentID string
+	relation Relation
+	lines    []string
+}
+
+// nodes returns nodes to render for the tree of errors rooted at err.
+func (r Renderer) nodes(err error) []renderNode {
+	nodes := []renderNode{}
+	ids := []string{}
+	frames := [][]runtime.Frame{}
+	Walk(err, func(node Node) WalkAction {
+		ids = ids[:node.Depth]
+		frames = frames[:node.Depth]
+
+		id := "e"
+		for _, i := range node.Path {
+			id += fmt.Sprintf("_%d", i)
+		}
+		parentID := ""
+		if node.Depth > 0 {
+			parentID = ids[node.Depth-1]
+		}
+
+		lines := strings.Split(node.Err.Error(), "\n")
+		lines = append(lines, reflect.TypeOf(node.Err).String())
+
+		d, ok := node.Err.(detailer)
+		if ok {
+			details := d.Details()
+			keys := make([]string, 0, len(details))
+			for key := range details {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				lines = append(lines, fmt.Sprintf("%s=%v", key, details[key]))
+			}
+		}
+
+		fs := Frames(node.Err)
+		if r.Frames > 0 && len(fs) > 0 && (node.Depth == 0 || !reflect.DeepEqual(fs, frames[node.Depth-1])) {
+			for i, f := range fs {
+				if i >= r.Frames {
+					break
+				}
+				lines = append(lines, fmt.Sprintf("%s %s:%d", f.Function, f.File, f.Line))
+			}
+		}
+
+		nodes = append(nodes, renderNode{id: id, parentID: parentID, relation: node.Relation, lines: lines})
+		ids = append(ids, id)
+		frames = append(frames, fs)
+		return WalkContinue
+	})
+	return nodes
+}
+
+// DOT renders the tree of errors rooted at err into w in Graphviz DOT language.
+func (r Renderer) DOT(w io.Writer, err error) E {
+	buf := new(bytes.Buffer)
+	buf.WriteString("digraph errors {\n")
+	buf.WriteString("\tnode [shape=box];\n")
+	for _, n := range r.nodes(err) {
+		lines := make([]string, 0, len(n.lines))
+		for _, line := range n.lines {
+			lines = append(lines, escapeDOT(line))
+		}
+		fmt.Fprintf(buf, "\t%s [label=\"%s\\l\"];\n", n.id, strings.Join(lines, "\\l"))
+		if n.parentID != "" {
+			fmt.Fprintf(buf, "\t%s -> %s [label=\"%s\"];\n", n.parentID, n.id, n.relation)
+		}
+	}
+	buf.WriteString("}\n")
+	_, e := w.Write(buf.Bytes())
+	if e != nil {
+		return WithStack(e)
+	}
+	return nil
+}
+
+// Mermaid renders the tree of errors rooted at err into w as a Mermaid flowchart.
+func (r Renderer) Mermaid(w io.Writer, err error) E {
+	buf := new(bytes.Buffer)
+	buf.WriteString("flowchart TD\n")
+	for _, n := range r.nodes(err) {
+		lines := make([]string, 0, len(n.lines))
+		for _, line := range n.lines {
+			lines = append(lines, escapeMermaid(line))
+		}
+		fmt.Fprintf(buf, "\t%s[\"%s\"]\n", n.id, strings.Join(lines, "<br/>"))
+		if n.parentID != "" {
+			fmt.Fprintf(buf, "\t%s -->|%s| %s\n", n.parentID, n.relation, n.id)
+		}
+	}
+	_, e := w.Write(buf.Bytes())
+	if e != nil {
+		return WithStack(e)
+	}
+	return nil
+}
+
+var dotReplacer = strings.NewReplacer( //nolint:gochecknoglobals
+	`\`, `\\`,
+	`"`, `\"`,
+	"\r", "",
+	"\t", " ",
+)
+
+func escapeDOT(s string) string {
+	return dotReplacer.Replace(s)
+}
+
+var mermaidReplacer = strings.NewReplacer( //nolint:gochecknoglobals
+	`&`, "#amp;",
+	`"`, "#quot;",
+	`<`, "#lt;",
+	`>`, "#gt;",
+	"\r", "",
+	"\t", " ",
+)
+
+func escapeMermaid(s string) string {
+	return mermaidReplacer.Replace(s)
+}
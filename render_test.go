@@ -0,0 +1,65 @@
+package errors_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	err := errors.Join(
+		fmt.Errorf(`quoted "%w"`, errors.Base("base")),
+		errors.Base("a < b"),
+	)
+
+	var buf bytes.Buffer
+	errE := errors.RenderDOT(&buf, err)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, `digraph errors {
+	node [shape=box];
+	e [label="quoted \"base\"\la < b\l*errors.msgJoinedError\l"];
+	e_0 [label="quoted \"base\"\l*fmt.wrapError\l"];
+	e -> e_0 [label="joined"];
+	e_0_0 [label="base\l*errors.errorString\l"];
+	e_0 -> e_0_0 [label="unwrap"];
+	e_1 [label="a < b\l*errors.errorString\l"];
+	e -> e_1 [label="joined"];
+}
+`, buf.String())
+
+	buf.Reset()
+	errE = errors.RenderMermaid(&buf, err)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, `flowchart TD
+	e["quoted #quot;base#quot;<br/>a #lt; b<br/>*errors.msgJoinedError"]
+	e_0["quoted #quot;base#quot;<br/>*fmt.wrapError"]
+	e -->|joined| e_0
+	e_0_0["base<br/>*errors.errorString"]
+	e_0 -->|unwrap| e_0_0
+	e_1["a #lt; b<br/>*errors.errorString"]
+	e -->|joined| e_1
+`, buf.String())
+}
+
+func TestRendererFrames(t *testing.T) {
+	t.Parallel()
+
+	err := errors.Wrap(errors.WithDetails(errors.New("error"), "key", "value"), "wrap")
+
+	var buf bytes.Buffer
+	errE := errors.Renderer{Frames: 1}.DOT(&buf, err)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	out := buf.String()
+	assert.Contains(t, out, `e -> e_0 [label="cause"];`)
+	assert.Contains(t, out, `e_0 [label="error\l*errors.noMsgError\lkey=value\lgitlab.com/tozd/go/errors_test.TestRendererFrames `)
+	assert.Contains(t, out, `e_0_0 [label="error\l*errors.fundamentalError\l"];`)
+	assert.Equal(t, 2, strings.Count(out, "TestRendererFrames"))
+}
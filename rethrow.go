@@ -0,0 +1,111 @@
+package errors
+
+import "fmt"
+
+type boundaryStackTracer interface {
+	BoundaryStackTrace() []uintptr
+}
+
+type placeholderBoundariesStackTracer interface {
+	BoundariesStackTraces() []placeholderStack
+}
+
+// Rethrow records the stack trace at the point Rethrow was called as
+// an additional boundary stack trace of err, even if err already has
+// a stack trace. If err does not have a stack trace, Rethrow records
+// the stack trace as err's stack trace instead, like WithStack does.
+// If err is nil, Rethrow returns nil.
+//
+// Use Rethrow when err crosses a goroutine or an API boundary, e.g., when
+// you receive it from a channel or from a worker pool. The stack trace of err
+// shows only where err was made (e.g., inside the worker), while the boundary
+// stack trace shows where it was received. Unlike Wrap, err does not become
+// a cause and the error message is not changed.
+//
+// Boundary stack traces are formatted by Formatter using the '+' flag after
+// the stack trace (each one after "rethrown at:" when using the '-' flag),
+// most recent first, and marshaled into JSON as the "boundaries" field.
+func Rethrow(err error) E {
+	if err == nil {
+		return nil
+	}
+
+	st := getExistingStackTrace(err)
+	var boundary []uintptr
+	if len(st) == 0 {
+		st = callers(0)
+	} else {
+		boundary = callers(0)
+	}
+
+	return record(&boundaryError{
+		annotation: newAnnotation(err, st),
+		boundary:   boundary,
+	}, EventRethrow, true, 0)
+}
+
+// getBoundariesToFormat unwraps err until it finds a cause or joined errors,
+// collecting boundary stack traces for formatting or marshaling.
+// It supports placeholder errors as well.
+func getBoundariesToFormat(err error) []interface{} {
+	boundaries := []interface{}{}
+	var cycle unwrapCycle
+	for err != nil && !cycle.repeated(err) {
+		placeholderErr, ok := err.(placeholderBoundariesStackTracer)
+		if ok {
+			for _, b := range placeholderErr.BoundariesStackTraces() {
+				if len(b) > 0 {
+					boundaries = append(boundaries, b)
+				}
+			}
+		}
+		b, ok := err.(boundaryStackTracer)
+		if ok {
+			st := b.BoundaryStackTrace()
+			if len(st) > 0 {
+				boundaries = append(boundaries, StackFormatter{st})
+			}
+		}
+		c, ok := err.(causer)
+		if ok && c.Cause() != nil {
+			break
+		}
+		e, ok := err.(unwrapperJoined)
+		if ok && len(e.Unwrap()) > 0 {
+			break
+		}
+		err = Unwrap(err)
+	}
+	if len(boundaries) == 0 {
+		return nil
+	}
+	return boundaries
+}
+
+// boundaryError wraps another error and has its own
+// stack and boundary stack, but does not have its own msg.
+type boundaryError struct {
+	annotation
+	boundary []uintptr
+}
+
+func (e *boundaryError) Error() string {
+	if isCalledFromRuntimePanic() {
+		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
+	}
+	return e.err.Error()
+}
+
+func (e *boundaryError) Format(s fmt.State, verb rune) {
+	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
+}
+
+func (e *boundaryError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
+}
+
+// BoundaryStackTrace returns the stack trace at the point Rethrow was called,
+// or nil if err did not have a stack trace at that point.
+func (e *boundaryError) BoundaryStackTrace() []uintptr {
+	return e.boundary
+}
@@ -0,0 +1,109 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+type boundaryStackTracer interface {
+	BoundaryStackTrace() []uintptr
+}
+
+func rethrowWorker(errs chan<- errors.E) {
+	errs <- errors.New("worker error")
+}
+
+func rethrowReceive() errors.E {
+	errs := make(chan errors.E, 1)
+	go rethrowWorker(errs)
+	return errors.Rethrow(<-errs)
+}
+
+func rethrowAgain() errors.E {
+	return errors.Rethrow(rethrowReceive())
+}
+
+func TestRethrow(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, errors.Rethrow(nil))
+
+	// Without a stack trace, the stack trace is recorded as usual.
+	base := errors.Base("base")
+	err := errors.Rethrow(base)
+	assert.EqualError(t, err, "base")
+	assert.ErrorIs(t, err, base)
+	assert.NotEmpty(t, err.StackTrace())
+	var b boundaryStackTracer
+	require.ErrorAs(t, err, &b)
+	assert.Empty(t, b.BoundaryStackTrace())
+	assert.NotContains(t, fmt.Sprintf("% -+#.1v", err), "rethrown at")
+
+	errs := make(chan errors.E, 1)
+	go rethrowWorker(errs)
+	workerErr := <-errs
+	err = errors.Rethrow(workerErr)
+	assert.EqualError(t, err, "worker error")
+	assert.ErrorIs(t, err, workerErr)
+	assert.Nil(t, errors.Cause(err))
+	// The stack trace is not changed.
+	assert.Equal(t, workerErr.StackTrace(), err.StackTrace())
+	require.ErrorAs(t, err, &b)
+	assert.NotEmpty(t, b.BoundaryStackTrace())
+}
+
+func TestRethrowFormat(t *testing.T) {
+	t.Parallel()
+
+	err := rethrowAgain()
+
+	assert.Regexp(t, `^worker error\n`+
+		`stack trace \(most recent call first\):\n`+
+		`gitlab.com/tozd/go/errors_test.rethrowWorker\n`+
+		`\t.+/rethrow_test.go:19\n`+
+		`(?:.+\n\t.+:\d+\n)*`+
+		`rethrown at:\n`+
+		`gitlab.com/tozd/go/errors_test.rethrowAgain\n`+
+		`\t.+/rethrow_test.go:29\n`+
+		`(?:.+\n\t.+:\d+\n)+`+
+		`rethrown at:\n`+
+		`gitlab.com/tozd/go/errors_test.rethrowReceive\n`+
+		`\t.+/rethrow_test.go:25\n`+
+		`gitlab.com/tozd/go/errors_test.rethrowAgain\n`+
+		`\t.+/rethrow_test.go:29\n`+
+		`(?:.+\n\t.+:\d+\n)+$`, fmt.Sprintf("% -+#.1v", err))
+
+	assert.Equal(t, "worker error\n", fmt.Sprintf("% -#.1v", err))
+}
+
+func TestRethrowJSON(t *testing.T) {
+	t.Parallel()
+
+	err := rethrowAgain()
+
+	data, e := json.Marshal(err)
+	require.NoError(t, e)
+	assert.Regexp(t, `^\{"boundaries":\[`+
+		`\[\{"name":"gitlab.com/tozd/go/errors_test.rethrowAgain","file":".+/rethrow_test.go","line":29\},.*\],`+
+		`\[\{"name":"gitlab.com/tozd/go/errors_test.rethrowReceive","file":".+/rethrow_test.go","line":25\},.*\]`+
+		`\],"error":"worker error","stack":\[\{"name":"gitlab.com/tozd/go/errors_test.rethrowWorker"`, string(data))
+
+	placeholder, errE := errors.UnmarshalJSON(data)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, fmt.Sprintf("% -+#.1v", err), fmt.Sprintf("% -+#.1v", placeholder))
+
+	data2, e := json.Marshal(placeholder)
+	require.NoError(t, e)
+	assert.Equal(t, string(data), string(data2))
+
+	// Boundaries of a cause are marshaled with the cause.
+	data, e = json.Marshal(errors.Wrap(err, "wrapped"))
+	require.NoError(t, e)
+	assert.Regexp(t, `^\{"cause":\{"boundaries":\[`, string(data))
+}
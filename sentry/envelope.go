@@ -0,0 +1,128 @@
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+const clientName = "gitlab.com/tozd/go/errors/sentry"
+
+// DSN is a parsed Sentry DSN (data source name), e.g.,
+// "https://public@o0.ingest.sentry.io/0".
+type DSN struct {
+	Scheme    string
+	PublicKey string
+	Host      string
+	Path      string
+	ProjectID string
+}
+
+// ParseDSN parses a Sentry DSN.
+func ParseDSN(dsn string) (*DSN, errors.E) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, errors.WithDetails(err, "dsn", dsn)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.WithDetails(errors.New("invalid DSN scheme"), "dsn", dsn)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.WithDetails(errors.New("DSN is missing public key"), "dsn", dsn)
+	}
+	i := strings.LastIndex(u.Path, "/")
+	if i < 0 || u.Path[i+1:] == "" {
+		return nil, errors.WithDetails(errors.New("DSN is missing project ID"), "dsn", dsn)
+	}
+	return &DSN{
+		Scheme:    u.Scheme,
+		PublicKey: u.User.Username(),
+		Host:      u.Host,
+		Path:      u.Path[:i],
+		ProjectID: u.Path[i+1:],
+	}, nil
+}
+
+// String returns the DSN as a string.
+func (d *DSN) String() string {
+	return fmt.Sprintf("%s://%s@%s%s/%s", d.Scheme, d.PublicKey, d.Host, d.Path, d.ProjectID)
+}
+
+// EnvelopeURL returns the URL of the envelope endpoint for the DSN.
+func (d *DSN) EnvelopeURL() string {
+	return fmt.Sprintf("%s://%s%s/api/%s/envelope/", d.Scheme, d.Host, d.Path, d.ProjectID)
+}
+
+// Envelope returns event wrapped into a Sentry envelope for dsn.
+func Envelope(event *Event, dsn *DSN) ([]byte, errors.E) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	header, err := json.Marshal(map[string]interface{}{
+		"event_id": event.EventID,
+		"sent_at":  time.Now().UTC(),
+		"dsn":      dsn.String(),
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	itemHeader, err := json.Marshal(map[string]interface{}{
+		"type":   "event",
+		"length": len(payload),
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	envelope := new(bytes.Buffer)
+	envelope.Write(header)
+	envelope.WriteString("\n")
+	envelope.Write(itemHeader)
+	envelope.WriteString("\n")
+	envelope.Write(payload)
+	envelope.WriteString("\n")
+	return envelope.Bytes(), nil
+}
+
+// Send sends event to Sentry at dsn using client.
+// If client is nil, http.DefaultClient is used.
+func Send(ctx context.Context, client *http.Client, dsn *DSN, event *Event) errors.E {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	envelope, errE := Envelope(event, dsn)
+	if errE != nil {
+		return errE
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dsn.EnvelopeURL(), bytes.NewReader(envelope))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", clientName, dsn.PublicKey))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.WithDetails(errors.New("unexpected response status"), "code", resp.StatusCode, "event", event.EventID)
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+// Package sentry converts errors into Sentry events and sends them to Sentry.
+//
+// Events are built from the whole tree of errors: the error, its causes, and
+// joined errors are converted into a list of exceptions (using Sentry's
+// exception groups for joined errors), with stack frames and details.
+// Events can be sent to any Sentry-compatible ingest endpoint as envelopes,
+// without depending on the Sentry SDK.
+package sentry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/errors/internal/shared"
+)
+
+// Frame is a stack frame in a Sentry event.
+type Frame struct {
+	Function string `json:"function,omitempty"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+// Stacktrace is a stack trace in a Sentry event.
+// Frames are ordered from the oldest call to the most recent one.
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+// Mechanism describes how an exception in a Sentry event
+// is related to other exceptions.
+type Mechanism struct {
+	Type             string `json:"type"`
+	Source           string `json:"source,omitempty"`
+	ExceptionID      int    `json:"exception_id"`
+	ParentID         *int   `json:"parent_id,omitempty"`
+	IsExceptionGroup bool   `json:"is_exception_group,omitempty"`
+}
+
+// Exception is an exception in a Sentry event.
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+	Mechanism  *Mechanism  `json:"mechanism,omitempty"`
+}
+
+// Exceptions is a list of exceptions in a Sentry event.
+// The top-level exception is the last one.
+type Exceptions struct {
+	Values []Exception `json:"values"`
+}
+
+// Event is a Sentry event.
+type Event struct {
+	EventID     string                            `json:"event_id"`
+	Timestamp   time.Time                         `json:"timestamp"`
+	Platform    string                            `json:"platform"`
+	Level       string                            `json:"level"`
+	Release     string                            `json:"release,omitempty"`
+	Environment string                            `json:"environment,omitempty"`
+	ServerName  string                            `json:"server_name,omitempty"`
+	Exception   Exceptions                        `json:"exception"`
+	Extra       map[string]interface{}            `json:"extra,omitempty"`
+	Contexts    map[string]map[string]interface{} `json:"contexts,omitempty"`
+	Fingerprint []string                          `json:"fingerprint,omitempty"`
+}
+
+// Builder builds Sentry events from errors.
+type Builder struct {
+	// Module path prefixes (e.g., "example.com/app") of stack frames which
+	// are in-app. If empty, frames which are not from the Go standard library
+	// are in-app, as determined by errors.Fingerprint.
+	InAppPrefixes []string `exhaustruct:"optional"`
+
+	// Put details of errors into event's contexts instead of into extra data.
+	DetailsAsContexts bool `exhaustruct:"optional"`
+
+	// Use errors.Fingerprint as event's fingerprint instead of
+	// letting Sentry group events.
+	Fingerprint bool `exhaustruct:"optional"`
+
+	Release     string `exhaustruct:"optional"`
+	Environment string `exhaustruct:"optional"`
+	ServerName  string `exhaustruct:"optional"`
+}
+
+// level is an error in the tree of errors for which an exception is made,
+// together with errors it wraps using Unwrap() error method.
+type level struct {
+	err  error
+	path string
+	id   int
+}
+
+// exceptionType returns the concrete Go type of err.
+func exceptionType(err error) string {
+	return reflect.TypeOf(err).String()
+}
+
+// Event builds a Sentry event from err.
+//
+// Exceptions are made from err, its cause (see errors.Cause), the cause of the
+// cause, and so on, and from joined errors (see errors.Unjoin), recursively.
+// They are ordered from the oldest to the newest, with err being the last.
+// Their relations are recorded using exception mechanisms. Each exception
+// has a type, the error message, and the stack trace (see errors.Frames).
+// The type is the concrete Go type of the first error which is not just a wrapper
+// made by the errors package (e.g., *fs.PathError) among the error and errors
+// it wraps using Unwrap() error method, stopping at a cause or joined errors as
+// errors.Frames does, or of the innermost such error if there is no such error.
+// The tree of errors is traversed using errors.Walk, so cycles are not
+// followed, and joined errors which do not have any additional information
+// (i.e., which are not formatted nor marshaled either) are skipped.
+//
+// Details of errors (see errors.AllDetails) are stored in extra data: details of err
+// directly and details of other errors under their path in the tree (e.g., "cause"
+// or "errors[1].cause"). When DetailsAsContexts is set, they are stored as contexts
+// instead, with details of err under the "error" context.
+//
+// If err is nil, Event returns nil.
+func (b Builder) Event(err error) *Event {
+	if err == nil {
+		return nil
+	}
+
+	event := &Event{
+		EventID:     newEventID(),
+		Timestamp:   time.Now().UTC(),
+		Platform:    "go",
+		Level:       "error",
+		Release:     b.Release,
+		Environment: b.Environment,
+		ServerName:  b.ServerName,
+		Exception:   Exceptions{Values: []Exception{}},
+		Extra:       nil,
+		Contexts:    nil,
+		Fingerprint: nil,
+	}
+
+	if b.Fingerprint {
+		event.Fingerprint = []string{errors.Fingerprint(err)}
+	}
+
+	// levels are levels on the path from the root to the current error, by depth.
+	levels := []level{}
+	// typed records for each exception if its type is already of an error
+	// which is not just a wrapper made by the errors package.
+	typed := []bool{}
+	errors.Walk(err, func(n errors.Node) errors.WalkAction {
+		levels = levels[:n.Depth]
+		if n.Relation == errors.RelationUnwrap {
+			// Errors wrapped using Unwrap() error method belong to the same exception.
+			l := levels[n.Depth-1]
+			if !typed[l.id] {
+				event.Exception.Values[l.id].Type = exceptionType(n.Err)
+				typed[l.id] = !shared.IsOwnError(n.Err)
+			}
+			levels = append(levels, l)
+			return errors.WalkContinue
+		}
+
+		var parent *level
+		source := ""
+		if n.Depth > 0 {
+			parent = &levels[n.Depth-1]
+			source = string(n.Relation)
+			if n.Relation != errors.RelationCause {
+				// We do not repeat an error without any additional information.
+				if shared.IsSubsumed(parent.err, n.Err) {
+					return errors.WalkSkip
+				}
+				source = fmt.Sprintf("errors[%d]", n.Path[len(n.Path)-1])
+			}
+		}
+
+		id := len(event.Exception.Values)
+		exception := Exception{
+			Type:       exceptionType(n.Err),
+			Value:      n.Err.Error(),
+			Stacktrace: b.stacktrace(errors.Frames(n.Err)),
+			Mechanism: &Mechanism{
+				Type:             "generic",
+				Source:           source,
+				ExceptionID:      id,
+				ParentID:         nil,
+				IsExceptionGroup: false,
+			},
+		}
+		path := ""
+		if parent != nil {
+			exception.Mechanism.Type = "chained"
+			parentID := parent.id
+			exception.Mechanism.ParentID = &parentID
+			if n.Relation != errors.RelationCause {
+				event.Exception.Values[parent.id].Mechanism.IsExceptionGroup = true
+			}
+			path = joinPath(parent.path, source)
+		}
+
+		b.addDetails(event, path, errors.AllDetails(n.Err))
+
+		event.Exception.Values = append(event.Exception.Values, exception)
+		typed = append(typed, !shared.IsOwnError(n.Err))
+		levels = append(levels, level{err: n.Err, path: path, id: id})
+		return errors.WalkContinue
+	})
+
+	// Sentry expects the oldest exception first and the top-level exception last.
+	values := event.Exception.Values
+	for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+		values[i], values[j] = values[j], values[i]
+	}
+
+	return event
+}
+
+func joinPath(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	if child == "" {
+		return parent
+	}
+	return parent + "." + child
+}
+
+func (b Builder) addDetails(event *Event, path string, details map[string]interface{}) {
+	if len(details) == 0 {
+		return
+	}
+
+	if b.DetailsAsContexts {
+		if event.Contexts == nil {
+			event.Contexts = map[string]map[string]interface{}{}
+		}
+		event.Contexts[joinPath("error", path)] = details
+		return
+	}
+
+	if event.Extra == nil {
+		event.Extra = map[string]interface{}{}
+	}
+	if path == "" {
+		for key, value := range details {
+			event.Extra[key] = value
+		}
+	} else {
+		event.Extra[path] = details
+	}
+}
+
+// stacktrace converts frames (most recent call first) into a Sentry stack trace.
+func (b Builder) stacktrace(frames []runtime.Frame) *Stacktrace {
+	if len(frames) == 0 {
+		return nil
+	}
+
+	st := &Stacktrace{Frames: make([]Frame, 0, len(frames))}
+	// Sentry expects the oldest call first.
+	for i := len(frames) - 1; i >= 0; i-- {
+		f := frames[i]
+		module, function := splitFunction(f.Function)
+		st.Frames = append(st.Frames, Frame{
+			Function: function,
+			Module:   module,
+			Filename: path.Base(f.File),
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    b.isInApp(module, f.Function),
+		})
+	}
+	return st
+}
+
+// isInApp returns true if the frame of function from module is in-app.
+func (b Builder) isInApp(module, function string) bool {
+	if len(b.InAppPrefixes) > 0 {
+		for _, prefix := range b.InAppPrefixes {
+			if module == prefix || strings.HasPrefix(module, prefix+"/") || strings.HasPrefix(module, prefix+"_test") {
+				return true
+			}
+		}
+		return false
+	}
+	return shared.IsInAppFunction(function)
+}
+
+// splitFunction splits full function name (e.g., "example.com/app/pkg.(*T).Method")
+// into the module (package import path) and the function name.
+func splitFunction(name string) (string, string) {
+	i := strings.LastIndex(name, "/")
+	j := strings.Index(name[i+1:], ".")
+	if j < 0 {
+		return "", name
+	}
+	j += i + 1
+	return name[:j], name[j+1:]
+}
+
+func newEventID() string {
+	id := make([]byte, 16) //nolint:mnd
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,205 @@
+package sentry_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/errors/sentry"
+)
+
+var errBase = errors.Base("not found")
+
+func makeError() errors.E {
+	cause := errors.WithDetails(errBase, "id", 42)
+	return errors.WithDetails(errors.Wrap(cause, "cannot load user"), "user", "alice")
+}
+
+func TestEvent(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, sentry.Builder{}.Event(nil))
+
+	event := sentry.Builder{Release: "1.0.0"}.Event(makeError())
+	assert.Regexp(t, `^[0-9a-f]{32}$`, event.EventID)
+	assert.Equal(t, "go", event.Platform)
+	assert.Equal(t, "error", event.Level)
+	assert.Equal(t, "1.0.0", event.Release)
+	assert.Nil(t, event.Fingerprint)
+
+	values := event.Exception.Values
+	require.Len(t, values, 2)
+
+	// The cause comes first.
+	// Types are of the first errors not from the errors package at each level.
+	assert.Equal(t, "*errors.errorString", values[0].Type)
+	assert.Equal(t, "not found", values[0].Value)
+	assert.Equal(t, "chained", values[0].Mechanism.Type)
+	assert.Equal(t, "cause", values[0].Mechanism.Source)
+	assert.Equal(t, 1, values[0].Mechanism.ExceptionID)
+	require.NotNil(t, values[0].Mechanism.ParentID)
+	assert.Equal(t, 0, *values[0].Mechanism.ParentID)
+
+	assert.Equal(t, "*errors.causeError", values[1].Type)
+	assert.Equal(t, "cannot load user", values[1].Value)
+	assert.Equal(t, "generic", values[1].Mechanism.Type)
+	assert.Equal(t, 0, values[1].Mechanism.ExceptionID)
+	assert.Nil(t, values[1].Mechanism.ParentID)
+
+	// The most recent call is the last frame.
+	for _, value := range values {
+		require.NotNil(t, value.Stacktrace)
+		frames := value.Stacktrace.Frames
+		require.NotEmpty(t, frames)
+		last := frames[len(frames)-1]
+		assert.Equal(t, "gitlab.com/tozd/go/errors/sentry_test", last.Module)
+		assert.Equal(t, "makeError", last.Function)
+		assert.Equal(t, "sentry_test.go", last.Filename)
+		assert.Contains(t, last.AbsPath, "/sentry/sentry_test.go")
+		assert.NotZero(t, last.Lineno)
+		assert.True(t, last.InApp)
+		for _, frame := range frames {
+			if frame.Module == "testing" || frame.Module == "runtime" {
+				assert.False(t, frame.InApp)
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"user":  "alice",
+		"cause": map[string]interface{}{"id": 42},
+	}, event.Extra)
+	assert.Nil(t, event.Contexts)
+
+	event = sentry.Builder{DetailsAsContexts: true, Fingerprint: true, InAppPrefixes: []string{"example.com/app"}}.Event(makeError())
+	assert.Nil(t, event.Extra)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"error":       {"user": "alice"},
+		"error.cause": {"id": 42},
+	}, event.Contexts)
+	assert.Equal(t, []string{errors.Fingerprint(makeError())}, event.Fingerprint)
+	for _, frame := range event.Exception.Values[0].Stacktrace.Frames {
+		assert.False(t, frame.InApp)
+	}
+}
+
+func TestEventJoined(t *testing.T) {
+	t.Parallel()
+
+	err := errors.Join(errors.New("first"), errors.Base("second"))
+	values := sentry.Builder{}.Event(err).Exception.Values
+	require.Len(t, values, 3)
+
+	assert.Equal(t, "*errors.errorString", values[0].Type)
+	assert.Equal(t, "second", values[0].Value)
+	assert.Equal(t, "errors[1]", values[0].Mechanism.Source)
+	assert.Nil(t, values[0].Stacktrace)
+	assert.Equal(t, "*errors.fundamentalError", values[1].Type)
+	assert.Equal(t, "first", values[1].Value)
+	assert.Equal(t, "errors[0]", values[1].Mechanism.Source)
+	assert.Equal(t, "*errors.msgJoinedError", values[2].Type)
+	assert.True(t, values[2].Mechanism.IsExceptionGroup)
+
+	values = sentry.Builder{}.Event(errors.Wrap(&fs.PathError{Op: "open", Path: "/file", Err: fs.ErrNotExist}, "cannot open")).Exception.Values
+	require.Len(t, values, 2)
+	assert.Equal(t, "*fs.PathError", values[0].Type)
+	assert.Equal(t, "*errors.causeError", values[1].Type)
+
+	// Types are not searched for past a cause.
+	values = sentry.Builder{}.Event(errors.WithStack(errors.Wrap(errBase, "cannot load"))).Exception.Values
+	require.Len(t, values, 2)
+	assert.Equal(t, "*errors.errorString", values[0].Type)
+	assert.Equal(t, "*errors.causeError", values[1].Type)
+
+	// But through errors wrapped using Unwrap() error method.
+	values = sentry.Builder{}.Event(errors.WithDetails(&fs.PathError{Op: "open", Path: "/file", Err: fs.ErrNotExist})).Exception.Values
+	require.Len(t, values, 1)
+	assert.Equal(t, "*fs.PathError", values[0].Type)
+
+	// The "with" error is not repeated.
+	values = sentry.Builder{}.Event(errors.WrapWith(errors.New("cause"), errBase)).Exception.Values
+	require.Len(t, values, 2)
+	assert.Equal(t, "cause", values[0].Value)
+	assert.Equal(t, "not found", values[1].Value)
+}
+
+func TestParseDSN(t *testing.T) {
+	t.Parallel()
+
+	dsn, errE := sentry.ParseDSN("https://public@example.com/sentry/42")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, &sentry.DSN{Scheme: "https", PublicKey: "public", Host: "example.com", Path: "/sentry", ProjectID: "42"}, dsn)
+	assert.Equal(t, "https://public@example.com/sentry/42", dsn.String())
+	assert.Equal(t, "https://example.com/sentry/api/42/envelope/", dsn.EnvelopeURL())
+
+	for _, invalid := range []string{"ftp://public@example.com/42", "https://example.com/42", "https://public@example.com/", "://"} {
+		_, errE := sentry.ParseDSN(invalid)
+		assert.Error(t, errE, invalid)
+	}
+}
+
+func TestSend(t *testing.T) {
+	t.Parallel()
+
+	var path, contentType, auth string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		contentType = r.Header.Get("Content-Type")
+		auth = r.Header.Get("X-Sentry-Auth")
+		body, _ = io.ReadAll(r.Body)
+		if r.URL.Path != "/api/7/envelope/" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dsn, errE := sentry.ParseDSN("http://key@" + server.Listener.Addr().String() + "/7")
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	event := sentry.Builder{}.Event(makeError())
+	errE = sentry.Send(context.Background(), server.Client(), dsn, event)
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	assert.Equal(t, "/api/7/envelope/", path)
+	assert.Equal(t, "application/x-sentry-envelope", contentType)
+	assert.Contains(t, auth, "sentry_key=key")
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(nil, len(body))
+	lines := []string{}
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.Len(t, lines, 3)
+
+	var header map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+	assert.Equal(t, event.EventID, header["event_id"])
+	assert.Equal(t, dsn.String(), header["dsn"])
+
+	var itemHeader map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &itemHeader))
+	assert.Equal(t, "event", itemHeader["type"])
+	assert.EqualValues(t, len(lines[2]), itemHeader["length"])
+
+	var payload sentry.Event
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &payload))
+	assert.Equal(t, event.EventID, payload.EventID)
+	assert.Len(t, payload.Exception.Values, 2)
+
+	dsn.ProjectID = "8"
+	errE = sentry.Send(context.Background(), server.Client(), dsn, event)
+	assert.EqualError(t, errE, "unexpected response status")
+	assert.Equal(t, http.StatusNotFound, errors.AllDetails(errE)["code"])
+}
@@ -0,0 +1,9 @@
+package errors
+
+import "gitlab.com/tozd/go/errors/internal/shared"
+
+func init() { //nolint:gochecknoinits
+	shared.IsOwnError = isOwnError
+	shared.IsSubsumed = isSubsumedError
+	shared.IsInAppFunction = isInAppFunction
+}
@@ -158,6 +158,89 @@ func (s StackFormatter) MarshalJSON() ([]byte, error) {
 	return output, nil
 }
 
+// Frames returns stack frames of err's stack trace, most recent call first.
+//
+// It uses the same stack trace which is used when formatting or marshaling
+// err: the first stack trace found when unwrapping err until a cause or
+// joined errors are found. Besides errors from this package, stack traces
+// of errors from other popular packages and of placeholder errors from
+// UnmarshalJSON are supported as well.
+//
+// If err does not have a stack trace, Frames returns nil.
+func Frames(err error) []runtime.Frame {
+	st := getExistingStackTrace(err)
+	if len(st) > 0 {
+		return stackFrames(st)
+	}
+
+	placeholderErr, ok := err.(placeholderStackTracer)
+	if !ok {
+		return nil
+	}
+	placeholderSt := placeholderErr.StackTrace()
+	if len(placeholderSt) == 0 {
+		return nil
+	}
+	return placeholderSt.frames()
+}
+
+// rewriteFrames returns frames rewritten with rewrite, omitting frames
+// for which rewrite returns false. If rewrite is nil, frames are returned as-is.
+func rewriteFrames(frames []runtime.Frame, rewrite func(runtime.Frame) (runtime.Frame, bool)) []runtime.Frame {
+	if rewrite == nil {
+		return frames
+	}
+	result := make([]runtime.Frame, 0, len(frames))
+	for _, f := range frames {
+		f, ok := rewrite(f)
+		if ok {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// rewriteStack returns stack (StackFormatter or placeholderStack) with frames
+// rewritten with rewrite, as placeholderStack which formats and marshals
+// in the same way. If rewrite is nil, stack is returned as-is.
+func rewriteStack(stack interface{}, rewrite func(runtime.Frame) (runtime.Frame, bool)) interface{} {
+	if rewrite == nil {
+		return stack
+	}
+	var frames []runtime.Frame
+	switch s := stack.(type) {
+	case StackFormatter:
+		frames = stackFrames(s.Stack)
+	case placeholderStack:
+		frames = s.frames()
+	default:
+		return stack
+	}
+	result := placeholderStack{}
+	for _, f := range rewriteFrames(frames, rewrite) {
+		result = append(result, placeholderFrame{
+			Name: f.Function,
+			File: f.File,
+			Line: f.Line,
+		})
+	}
+	return result
+}
+
+// stackFrames returns frames of stack trace st.
+func stackFrames(st []uintptr) []runtime.Frame {
+	result := []runtime.Frame{}
+	frames := runtime.CallersFrames(st)
+	for {
+		f, more := frames.Next()
+		result = append(result, f)
+		if !more {
+			break
+		}
+	}
+	return result
+}
+
 func callers(extraSkip int) []uintptr {
 	const depth = 32
 	var pcs [depth]uintptr
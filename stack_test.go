@@ -284,3 +284,23 @@ func caller() frame {
 //
 //go:noinline
 func noinline() {}
+
+func TestFrames(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, Frames(nil))
+	assert.Nil(t, Frames(Base("error")))
+
+	err := New("error")
+	frames := Frames(err)
+	require.NotEmpty(t, frames)
+	assert.Equal(t, "gitlab.com/tozd/go/errors.TestFrames", frames[0].Function)
+	assert.Equal(t, len(err.StackTrace()), len(frames))
+
+	// The stack trace is found by unwrapping.
+	assert.Equal(t, frames, Frames(BaseWrap(err, "wrapped")))
+
+	placeholder, errE := UnmarshalJSON([]byte(`{"error":"error","stack":[{"name":"main.main","file":"/main.go","line":10}]}`))
+	require.NoError(t, errE)
+	assert.Equal(t, []runtime.Frame{{Function: "main.main", File: "/main.go", Line: 10}}, Frames(placeholder))
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+var errUsage = errors.Base("usage error")
+
+func run() error {
+	switch os.Args[1] {
+	case "success":
+		return nil
+	case "usage":
+		return errors.WithExitCode(errors.WithDetails(errUsage, "arg", "foo"), 2) //nolint:mnd
+	default:
+		return errors.New("internal error")
+	}
+}
+
+func main() {
+	errors.Main(run)
+}
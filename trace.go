@@ -0,0 +1,235 @@
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+type returnTracer interface {
+	ReturnTrace() []uintptr
+}
+
+type placeholderReturnTracer interface {
+	ReturnTrace() placeholderStack
+}
+
+type returnTraceOmitter interface {
+	ReturnTraceOmitted() int
+}
+
+// maxReturnTrace is the maximum number of return points kept in a return trace.
+const maxReturnTrace = 100
+
+// returnTrace is a list of program counters of return points
+// shared between all calls to Trace on the same error.
+type returnTrace struct {
+	mu  sync.Mutex
+	pcs []uintptr
+	// The number of earliest return points omitted to keep at most
+	// maxReturnTrace return points.
+	omitted int
+}
+
+func (t *returnTrace) add(pc uintptr) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.pcs) >= maxReturnTrace {
+		copy(t.pcs, t.pcs[1:])
+		t.pcs[len(t.pcs)-1] = pc
+		t.omitted++
+		return
+	}
+	t.pcs = append(t.pcs, pc)
+}
+
+func (t *returnTrace) get() []uintptr {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]uintptr(nil), t.pcs...)
+}
+
+func (t *returnTrace) getOmitted() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.omitted
+}
+
+// Trace records the point at which it was called into the return trace of err.
+// If err does not have a stack trace, stack strace is recorded as well.
+// If err is nil, Trace returns nil.
+//
+// A stack trace shows where the error was made, but not the path the error
+// took back up through returns, which matters when errors are passed between
+// goroutines or through callbacks. Call Trace when returning an error
+// to record that path:
+//
+//	return errors.Trace(err)
+//
+// Trace is cheap: it records only one program counter. All points are recorded
+// into one shared list: if err already has a return trace (made by a previous
+// call to Trace), Trace appends to it in place and returns err as-is. Otherwise
+// err is wrapped once to hold the return trace. This means that Trace mutates
+// err: the new point is visible through all references to err and to errors
+// wrapping it (e.g., when the same error is returned to multiple callers).
+//
+// Only the last 100 points are kept (e.g., when an error is traced in a loop),
+// together with the number of earlier points which were omitted.
+//
+// The return trace is formatted by Formatter using the '0' flag and marshaled
+// into JSON as the "return_trace" field, with the number of omitted points
+// in the "return_trace_omitted" field.
+// Wrap and other functions which make err the cause of a new error
+// start a new return trace for the new error.
+func Trace(err error) E {
+	if err == nil {
+		return nil
+	}
+
+	var pcs [1]uintptr
+	// Skip runtime.Callers and Trace.
+	runtime.Callers(2, pcs[:]) //nolint:mnd
+
+	e, ok := err.(E) //nolint:errorlint
+	if ok {
+		t := getTraceError(e)
+		if t != nil {
+			t.trace.add(pcs[0])
+			return e
+		}
+	}
+
+	st := getExistingStackTrace(err)
+	newStack := len(st) == 0
+	if newStack {
+		st = callers(0)
+	}
+
+	return record(&traceError{
+		annotation: newAnnotation(err, st),
+		trace:      &returnTrace{pcs: []uintptr{pcs[0]}}, //nolint:exhaustruct
+	}, EventTrace, newStack, 0)
+}
+
+// getTraceError unwraps err until it finds traceError, a cause, or joined errors.
+func getTraceError(err error) *traceError {
+	var cycle unwrapCycle
+	for err != nil && !cycle.repeated(err) {
+		t, ok := err.(*traceError) //nolint:errorlint
+		if ok {
+			return t
+		}
+		c, ok := err.(causer)
+		if ok && c.Cause() != nil {
+			return nil
+		}
+		e, ok := err.(unwrapperJoined)
+		if ok && len(e.Unwrap()) > 0 {
+			return nil
+		}
+		err = Unwrap(err)
+	}
+	return nil
+}
+
+// getExistingReturnTrace unwraps err until it finds a return trace, a cause, or joined errors.
+func getExistingReturnTrace(err error) []uintptr {
+	var cycle unwrapCycle
+	for err != nil && !cycle.repeated(err) {
+		t, ok := err.(returnTracer)
+		if ok {
+			return t.ReturnTrace()
+		}
+		c, ok := err.(causer)
+		if ok && c.Cause() != nil {
+			return nil
+		}
+		e, ok := err.(unwrapperJoined)
+		if ok && len(e.Unwrap()) > 0 {
+			return nil
+		}
+		err = Unwrap(err)
+	}
+	return nil
+}
+
+// getReturnTraceOmitted unwraps err until it finds a return trace, a cause,
+// or joined errors, and returns the number of points omitted from the
+// return trace. It supports placeholder errors as well.
+func getReturnTraceOmitted(err error) int {
+	if _, ok := err.(placeholderReturnTracer); ok { //nolint:errorlint
+		return getOmittedParts(err).returnTrace
+	}
+	var cycle unwrapCycle
+	for err != nil && !cycle.repeated(err) {
+		t, ok := err.(returnTraceOmitter)
+		if ok {
+			return t.ReturnTraceOmitted()
+		}
+		c, ok := err.(causer)
+		if ok && c.Cause() != nil {
+			return 0
+		}
+		e, ok := err.(unwrapperJoined)
+		if ok && len(e.Unwrap()) > 0 {
+			return 0
+		}
+		err = Unwrap(err)
+	}
+	return 0
+}
+
+// getReturnTraceToFormat returns the return trace of err for formatting
+// or marshaling, supporting placeholder errors as well. It returns nil
+// if err does not have a return trace.
+func getReturnTraceToFormat(err error) interface{} {
+	rt := getExistingReturnTrace(err)
+	if len(rt) > 0 {
+		return StackFormatter{rt}
+	}
+	placeholderErr, ok := err.(placeholderReturnTracer)
+	if ok {
+		placeholderRt := placeholderErr.ReturnTrace()
+		if len(placeholderRt) > 0 {
+			return placeholderRt
+		}
+	}
+	return nil
+}
+
+// traceError wraps another error and has its own
+// stack and return trace, but does not have its own msg.
+type traceError struct {
+	annotation
+	trace *returnTrace
+}
+
+func (e *traceError) Error() string {
+	if isCalledFromRuntimePanic() {
+		return fmt.Sprintf("% -+#.1v", Formatter{Error: e})
+	}
+	return e.err.Error()
+}
+
+func (e *traceError) Format(s fmt.State, verb rune) {
+	_, _ = fmt.Fprintf(s, formatString(s, verb), Formatter{Error: e})
+}
+
+func (e *traceError) MarshalJSON() ([]byte, error) {
+	return marshalJSONError(e)
+}
+
+// ReturnTrace returns program counters of points at which Trace
+// was called, in the order of calls.
+func (e *traceError) ReturnTrace() []uintptr {
+	return e.trace.get()
+}
+
+// ReturnTraceOmitted returns the number of earliest points at which
+// Trace was called which were omitted from the return trace.
+func (e *traceError) ReturnTraceOmitted() int {
+	return e.trace.getOmitted()
+}
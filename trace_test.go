@@ -0,0 +1,190 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+type returnTracer interface {
+	ReturnTrace() []uintptr
+}
+
+func traceInner() errors.E {
+	return errors.Trace(errors.New("error"))
+}
+
+func traceMiddle() errors.E {
+	return errors.Trace(traceInner())
+}
+
+func traceOuter() errors.E {
+	return errors.Trace(traceMiddle())
+}
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, errors.Trace(nil))
+
+	base := errors.Base("base")
+	err := errors.Trace(base)
+	assert.EqualError(t, err, "base")
+	assert.ErrorIs(t, err, base)
+	assert.NotEmpty(t, err.StackTrace())
+
+	err = traceOuter()
+	assert.EqualError(t, err, "error")
+	var rt returnTracer
+	require.ErrorAs(t, err, &rt)
+	assert.Len(t, rt.ReturnTrace(), 3)
+
+	// No new wrapper is made, existing return trace is extended.
+	assert.Same(t, err, errors.Trace(err))
+	assert.Len(t, rt.ReturnTrace(), 4)
+
+	// Also through errors without their own return trace.
+	withDetails := errors.WithDetails(err, "key", "value")
+	assert.Same(t, withDetails, errors.Trace(withDetails))
+	assert.Len(t, rt.ReturnTrace(), 5)
+
+	public := errors.WithPublicMessage(err, "public")
+	assert.Same(t, public, errors.Trace(public))
+	assert.Len(t, rt.ReturnTrace(), 6)
+	assert.Contains(t, fmt.Sprintf("%0v", public), "gitlab.com/tozd/go/errors_test.traceMiddle\n")
+
+	// But not through a cause.
+	wrapped := errors.Wrap(err, "wrapped")
+	traced := errors.Trace(wrapped)
+	assert.NotSame(t, wrapped, traced)
+	require.ErrorAs(t, traced, &rt)
+	assert.Len(t, rt.ReturnTrace(), 1)
+}
+
+func TestTraceConcurrent(t *testing.T) {
+	t.Parallel()
+
+	err := errors.Trace(errors.New("error"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = errors.Trace(err)
+		}()
+	}
+	wg.Wait()
+
+	var rt returnTracer
+	require.ErrorAs(t, err, &rt)
+	assert.Len(t, rt.ReturnTrace(), 11)
+}
+
+func TestTraceFormat(t *testing.T) {
+	t.Parallel()
+
+	err := errors.Wrap(traceOuter(), "wrapped")
+
+	// Return trace is not formatted without the flag.
+	assert.NotContains(t, fmt.Sprintf("% -+#.1v", err), "return trace")
+
+	assert.Regexp(t, `^wrapped\n`+
+		`stack trace \(most recent call first\):\n`+
+		`gitlab.com/tozd/go/errors_test.TestTraceFormat\n`+
+		`\t.+/trace_test.go:\d+\n`+
+		`(?:.+\n\t.+:\d+\n)+`+
+		`\n`+
+		`the above error was caused by the following error:\n`+
+		`\n`+
+		`error\n`+
+		`stack trace \(most recent call first\):\n`+
+		`gitlab.com/tozd/go/errors_test.traceInner\n`+
+		`\t.+/trace_test.go:20\n`+
+		`(?:.+\n\t.+:\d+\n)+`+
+		`return trace \(first return first\):\n`+
+		`gitlab.com/tozd/go/errors_test.traceInner\n`+
+		`\t.+/trace_test.go:20\n`+
+		`gitlab.com/tozd/go/errors_test.traceMiddle\n`+
+		`\t.+/trace_test.go:24\n`+
+		`gitlab.com/tozd/go/errors_test.traceOuter\n`+
+		`\t.+/trace_test.go:28\n$`, errors.Sprint(err, errors.FormatOptions{
+		Spacing:     true,
+		Stack:       true,
+		Help:        true,
+		Details:     true,
+		ReturnTrace: true,
+		Mode:        errors.FormatRecursive,
+	}))
+
+	assert.Regexp(t, `^error\n`+
+		`gitlab.com/tozd/go/errors_test.traceInner\n`+
+		`\t.+/trace_test.go:20\n`+
+		`gitlab.com/tozd/go/errors_test.traceMiddle\n`+
+		`\t.+/trace_test.go:24\n`+
+		`gitlab.com/tozd/go/errors_test.traceOuter\n`+
+		`\t.+/trace_test.go:28\n$`, fmt.Sprintf("%0v", traceOuter()))
+}
+
+func TestTraceJSON(t *testing.T) {
+	t.Parallel()
+
+	err := errors.Wrap(traceOuter(), "wrapped")
+
+	data, e := json.Marshal(err)
+	require.NoError(t, e)
+	assert.Regexp(t, `"cause":\{"error":"error","return_trace":\[`+
+		`\{"name":"gitlab.com/tozd/go/errors_test.traceInner","file":".+/trace_test.go","line":20\},`+
+		`\{"name":"gitlab.com/tozd/go/errors_test.traceMiddle","file":".+/trace_test.go","line":24\},`+
+		`\{"name":"gitlab.com/tozd/go/errors_test.traceOuter","file":".+/trace_test.go","line":28\}`+
+		`\],"stack":\[`, string(data))
+
+	placeholder, errE := errors.UnmarshalJSON(data)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	// Before Go 1.23 the '0' flag is ignored when combined with the '-' flag.
+	assert.Equal(t, fmt.Sprintf("% +0#.1v", err), fmt.Sprintf("% +0#.1v", placeholder))
+	assert.Contains(t, fmt.Sprintf("% +0#.1v", placeholder), "gitlab.com/tozd/go/errors_test.traceMiddle\n")
+
+	data2, e := json.Marshal(placeholder)
+	require.NoError(t, e)
+	assert.Equal(t, string(data), string(data2))
+}
+
+func TestTraceLimit(t *testing.T) {
+	t.Parallel()
+
+	err := errors.Trace(errors.New("error"))
+	for i := 0; i < 104; i++ {
+		_ = errors.Trace(err)
+	}
+
+	var rt returnTracer
+	require.ErrorAs(t, err, &rt)
+	assert.Len(t, rt.ReturnTrace(), 100)
+
+	assert.Regexp(t, `\nand 5 earlier return points\n$`, fmt.Sprintf("%0v", err))
+	assert.Regexp(t, ` return_trace_omitted=5\n$`, errors.Sprint(err, errors.FormatOptions{ReturnTrace: true, Mode: errors.FormatLogfmt}))
+
+	data, e := json.Marshal(err)
+	require.NoError(t, e)
+	assert.Contains(t, string(data), `"return_trace_omitted":5`)
+
+	placeholder, errE := errors.UnmarshalJSON(data)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, fmt.Sprintf("%0v", err), fmt.Sprintf("%0v", placeholder))
+
+	data2, e := json.Marshal(placeholder)
+	require.NoError(t, e)
+	assert.Equal(t, string(data), string(data2))
+
+	// Without a return trace, the field is a detail.
+	placeholder, errE = errors.UnmarshalJSON([]byte(`{"error":"error","return_trace_omitted":5}`))
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, map[string]interface{}{"return_trace_omitted": float64(5)}, errors.AllDetails(placeholder))
+}
@@ -0,0 +1,188 @@
+package errors
+
+import (
+	"reflect"
+)
+
+// Relation is the relation of an error in the tree of errors to its parent.
+type Relation string
+
+// Relations of errors to their parents.
+const (
+	// The error is the root of the tree.
+	RelationRoot Relation = "root"
+	// The error is returned by parent's Unwrap() error method,
+	// but it is not parent's cause.
+	RelationUnwrap Relation = "unwrap"
+	// The error is parent's cause (see Cause).
+	RelationCause Relation = "cause"
+	// The error is one of errors returned by parent's Unwrap() []error
+	// method (see Unjoin), and parent does not have a cause.
+	RelationJoined Relation = "joined"
+	// The error is one of errors returned by parent's Unwrap() []error
+	// method, and parent also has a cause (e.g., the "with" error of WrapWith).
+	RelationWith Relation = "with"
+)
+
+// WalkAction tells Walk how to continue after visiting an error.
+type WalkAction int
+
+const (
+	// WalkContinue continues walking, visiting errors wrapped by the current error.
+	WalkContinue WalkAction = iota
+	// WalkSkip continues walking, but skips errors wrapped by the current error.
+	WalkSkip
+	// WalkStop stops walking.
+	WalkStop
+)
+
+// Node is an error in the tree of errors visited by Walk.
+type Node struct {
+	// The error.
+	Err error
+
+	// Depth of the error in the tree. The root has depth 0.
+	Depth int
+
+	// Path from the root to the error, as indices of children at each
+	// level. The root has an empty path.
+	Path []int
+
+	// Relation of the error to its parent.
+	Relation Relation
+}
+
+// child is a wrapped error with its relation to the wrapping error.
+type child struct {
+	err      error
+	relation Relation
+}
+
+// children returns errors directly wrapped by err with their relations.
+//
+// An error which is both the cause and one of the joined errors (e.g., the
+// "err" error of WrapWith) is returned only once, as the cause.
+func children(err error) []child {
+	var cause error
+	c, ok := err.(causer) //nolint:errorlint
+	if ok {
+		cause = c.Cause()
+	}
+
+	result := []child{}
+	causeFound := false
+
+	switch u := err.(type) { //nolint:errorlint
+	case unwrapperJoined:
+		for _, er := range u.Unwrap() {
+			if er == nil {
+				continue
+			}
+			switch {
+			case cause != nil && isSameError(er, cause):
+				if !causeFound {
+					causeFound = true
+					result = append(result, child{er, RelationCause})
+				}
+			case cause != nil:
+				result = append(result, child{er, RelationWith})
+			default:
+				result = append(result, child{er, RelationJoined})
+			}
+		}
+	case unwrapper:
+		er := u.Unwrap()
+		if er != nil {
+			if cause != nil && isSameError(er, cause) {
+				causeFound = true
+				result = append(result, child{er, RelationCause})
+			} else {
+				result = append(result, child{er, RelationUnwrap})
+			}
+		}
+	}
+
+	if cause != nil && !causeFound {
+		result = append(result, child{cause, RelationCause})
+	}
+
+	return result
+}
+
+// isSameError returns true if a and b are the same error value.
+// Errors of types which are not comparable are never the same.
+func isSameError(a, b error) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// Walk traverses the tree of errors rooted at err depth-first, calling fn
+// for each error in the tree, starting with err itself.
+//
+// The tree consists of err itself, followed by errors obtained from its
+// Unwrap() error, Unwrap() []error, and Cause() error methods, recursively.
+// Each visited Node reports the relation of the error to its parent (see
+// Relation), its depth, and its path from the root.
+// An error which is both the cause and one of the joined errors (e.g., the
+// "err" error of WrapWith) is visited only once, as the cause.
+//
+// The return value of fn controls how the walk continues: WalkContinue
+// continues into errors wrapped by the current error, WalkSkip skips them,
+// and WalkStop stops the walk.
+//
+// Walk is cycle-safe: an error which is the same as one of its ancestors
+// (wraps itself, directly or indirectly) is not visited again.
+//
+// If err is nil, fn is not called.
+func Walk(err error, fn func(node Node) WalkAction) {
+	if err == nil {
+		return
+	}
+	walk(Node{Err: err, Depth: 0, Path: []int{}, Relation: RelationRoot}, []error{}, fn)
+}
+
+// walk visits node and its children. It returns false if walking should stop.
+func walk(node Node, ancestors []error, fn func(node Node) WalkAction) bool {
+	switch fn(node) {
+	case WalkStop:
+		return false
+	case WalkSkip:
+		return true
+	case WalkContinue:
+	}
+
+	ancestors = append(ancestors, node.Err)
+	for i, c := range children(node.Err) {
+		if isAncestor(c.err, ancestors) {
+			continue
+		}
+		if !walk(childNode(node, i, c), ancestors, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// childNode returns the node for c, the i-th child of parent.
+func childNode(parent Node, i int, c child) Node {
+	path := make([]int, len(parent.Path)+1)
+	copy(path, parent.Path)
+	path[len(parent.Path)] = i
+	return Node{Err: c.err, Depth: parent.Depth + 1, Path: path, Relation: c.relation}
+}
+
+func isAncestor(err error, ancestors []error) bool {
+	return ancestorIndex(err, ancestors) >= 0
+}
+
+// ancestorIndex returns the index of err among ancestors or -1 if it is not among them.
+func ancestorIndex(err error, ancestors []error) int {
+	for i, a := range ancestors {
+		if isSameError(err, a) {
+			return i
+		}
+	}
+	return -1
+}
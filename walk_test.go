@@ -0,0 +1,163 @@
+package errors_test
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+type walkedNode struct {
+	Msg      string
+	Depth    int
+	Path     []int
+	Relation errors.Relation
+}
+
+func walkAll(err error, action func(node errors.Node) errors.WalkAction) []walkedNode {
+	nodes := []walkedNode{}
+	errors.Walk(err, func(node errors.Node) errors.WalkAction {
+		nodes = append(nodes, walkedNode{node.Err.Error(), node.Depth, node.Path, node.Relation})
+		if action != nil {
+			return action(node)
+		}
+		return errors.WalkContinue
+	})
+	return nodes
+}
+
+type cycleError struct {
+	err error
+}
+
+func (e *cycleError) Error() string {
+	return "cycle"
+}
+
+func (e *cycleError) Unwrap() error {
+	return e.err
+}
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+
+	base := errors.Base("base")
+	with := errors.Base("with")
+
+	tests := []struct {
+		Name  string
+		Err   error
+		Nodes []walkedNode
+	}{
+		{
+			"nil",
+			nil,
+			[]walkedNode{},
+		},
+		{
+			"base",
+			base,
+			[]walkedNode{
+				{"base", 0, []int{}, errors.RelationRoot},
+			},
+		},
+		{
+			"withStack",
+			errors.WithStack(base),
+			[]walkedNode{
+				{"base", 0, []int{}, errors.RelationRoot},
+				{"base", 1, []int{0}, errors.RelationUnwrap},
+			},
+		},
+		{
+			"wrap",
+			errors.Wrap(base, "wrap"),
+			[]walkedNode{
+				{"wrap", 0, []int{}, errors.RelationRoot},
+				{"base", 1, []int{0}, errors.RelationCause},
+			},
+		},
+		{
+			"wrapWith",
+			errors.WrapWith(base, with),
+			[]walkedNode{
+				{"with", 0, []int{}, errors.RelationRoot},
+				{"with", 1, []int{0}, errors.RelationWith},
+				{"base", 1, []int{1}, errors.RelationCause},
+			},
+		},
+		{
+			"join",
+			errors.Join(base, errors.Wrap(with, "wrap")),
+			[]walkedNode{
+				{"base\nwrap", 0, []int{}, errors.RelationRoot},
+				{"base", 1, []int{0}, errors.RelationJoined},
+				{"wrap", 1, []int{1}, errors.RelationJoined},
+				{"with", 2, []int{1, 0}, errors.RelationCause},
+			},
+		},
+		{
+			"fmt",
+			fmt.Errorf("fmt: %w", io.EOF),
+			[]walkedNode{
+				{"fmt: EOF", 0, []int{}, errors.RelationRoot},
+				{"EOF", 1, []int{0}, errors.RelationUnwrap},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.Nodes, walkAll(tt.Err, nil))
+		})
+	}
+}
+
+func TestWalkActions(t *testing.T) {
+	t.Parallel()
+
+	err := errors.Join(errors.Wrap(errors.Base("first"), "wrap"), errors.Base("second"))
+
+	nodes := walkAll(err, func(node errors.Node) errors.WalkAction {
+		if node.Err.Error() == "wrap" {
+			return errors.WalkSkip
+		}
+		return errors.WalkContinue
+	})
+	assert.Equal(t, []walkedNode{
+		{"wrap\nsecond", 0, []int{}, errors.RelationRoot},
+		{"wrap", 1, []int{0}, errors.RelationJoined},
+		{"second", 1, []int{1}, errors.RelationJoined},
+	}, nodes)
+
+	nodes = walkAll(err, func(node errors.Node) errors.WalkAction {
+		if node.Err.Error() == "first" {
+			return errors.WalkStop
+		}
+		return errors.WalkContinue
+	})
+	assert.Equal(t, []walkedNode{
+		{"wrap\nsecond", 0, []int{}, errors.RelationRoot},
+		{"wrap", 1, []int{0}, errors.RelationJoined},
+		{"first", 2, []int{0, 0}, errors.RelationCause},
+	}, nodes)
+}
+
+func TestWalkCycle(t *testing.T) {
+	t.Parallel()
+
+	err := &cycleError{}
+	err.err = errors.WithStack(err)
+
+	assert.Equal(t, []walkedNode{
+		{"cycle", 0, []int{}, errors.RelationRoot},
+		{"cycle", 1, []int{0}, errors.RelationUnwrap},
+	}, walkAll(err, nil))
+}